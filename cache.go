@@ -0,0 +1,135 @@
+package astparser
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"go/ast"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Version is the astparser version, parse cache entries of other versions are ignored.
+// It must be bumped with every change of the parse output, like new ParsedFile fields
+// or changed walker results, so existing cache dirs never serve stale files.
+const Version = "0.7.0"
+
+func init() {
+	gob.Register(TypeSimple{})
+	gob.Register(TypeArray{})
+	gob.Register(TypeMap{})
+	gob.Register(TypeCustom{})
+	gob.Register(TypePointer{})
+	gob.Register(TypeInterfaceValue{})
+}
+
+// fileCache stores parsed files in a directory keyed by file content hash.
+// Cache errors are never fatal: broken entries are treated as misses and
+// failed writes are ignored.
+type fileCache struct {
+	dir string
+	// fingerprint is a part of the key made of the config options files are loaded with.
+	fingerprint string
+}
+
+func newFileCache(cfg Config) *fileCache {
+	if cfg.CacheDir == "" {
+		return nil
+	}
+	fingerprint := fmt.Sprintf("%s/%s %q generated=%t tests=%t/%t", cfg.GOOS, cfg.GOARCH, cfg.BuildTags,
+		cfg.SkipGenerated, cfg.IncludeTests, cfg.IncludeExternalTests)
+	return &fileCache{dir: cfg.CacheDir, fingerprint: fingerprint}
+}
+
+func (c *fileCache) get(path string, content []byte) (ParsedFile, bool) {
+	data, err := ioutil.ReadFile(c.entryPath(path, content))
+	if err != nil {
+		return ParsedFile{}, false
+	}
+
+	var file ParsedFile
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&file); err != nil {
+		return ParsedFile{}, false
+	}
+	return file, true
+}
+
+func (c *fileCache) put(path string, content []byte, file ParsedFile) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(file); err != nil {
+		return
+	}
+
+	entry := c.entryPath(path, content)
+	if err := os.MkdirAll(filepath.Dir(entry), 0755); err != nil {
+		return
+	}
+	// write to a temp file first so concurrent loads never read partial entries.
+	tmp, err := ioutil.TempFile(filepath.Dir(entry), "tmp-")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(buf.Bytes())
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), entry)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// entryPath returns cache entry path like `dir/ab/abcdef...`.
+func (c *fileCache) entryPath(path string, content []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00", Version, c.fingerprint, path)
+	h.Write(content)
+	key := hex.EncodeToString(h.Sum(nil))
+	return filepath.Join(c.dir, key[:2], key)
+}
+
+// typeCustomGob is TypeCustom without the ast expression gob can't encode.
+type typeCustomGob struct {
+	Alias     bool
+	AliasType Type
+	Name      string
+	Qualifier string
+}
+
+// GobEncode implements gob.GobEncoder to store TypeCustom in the parse cache.
+func (t TypeCustom) GobEncode() ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(typeCustomGob{
+		Alias:     t.Alias,
+		AliasType: t.AliasType,
+		Name:      t.Name,
+		Qualifier: t.Qualifier,
+	})
+	return buf.Bytes(), err
+}
+
+// GobDecode implements gob.GobDecoder. Expr is restored for
+// qualified types only and has no positions.
+func (t *TypeCustom) GobDecode(data []byte) error {
+	var v typeCustomGob
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&v); err != nil {
+		return err
+	}
+
+	*t = TypeCustom{Alias: v.Alias, AliasType: v.AliasType, Name: v.Name, Qualifier: v.Qualifier}
+	t.Expr = qualifiedExpr(v.Qualifier, v.Name)
+	return nil
+}
+
+// qualifiedExpr restores TypeCustom.Expr of a decoded qualified type, nil if there is no qualifier.
+func qualifiedExpr(qualifier, name string) ast.Expr {
+	if qualifier == "" {
+		return nil
+	}
+	return &ast.SelectorExpr{X: ast.NewIdent(qualifier), Sel: ast.NewIdent(name)}
+}
@@ -0,0 +1,96 @@
+package astparser
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoad_cache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "astparser-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := Config{InputDir: "fixtures_test", CacheDir: dir}
+	want, err := Load(cfg)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	entries, err := filepath.Glob(filepath.Join(dir, "*", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("have %d cache entries, want %d", len(entries), len(want))
+	}
+
+	got, err := Load(cfg)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	for name, w := range want {
+		g := got[name]
+		// restored selector expressions have no positions.
+		if !reflect.DeepEqual(g.Imports, w.Imports) || !reflect.DeepEqual(g.Constants, w.Constants) ||
+			!reflect.DeepEqual(g.Types, w.Types) || len(g.Structs) != len(w.Structs) {
+			t.Errorf("cached %s differs:\nhave %+v\nwant %+v", name, g, w)
+		}
+	}
+
+	if name := "struct_with_primitives.go"; !reflect.DeepEqual(got[name], want[name]) {
+		t.Errorf("cached %s differs:\nhave %+v\nwant %+v", name, got[name], want[name])
+	}
+
+	created := got["doc.go"].Structs[0].Fields[1].FieldType.(TypeCustom)
+	if created.Qualifier != "time" || created.Name != "Time" || created.Expr == nil {
+		t.Errorf("unexpected cached type %+v", created)
+	}
+}
+
+func TestFileCache_contentChange(t *testing.T) {
+	dir, err := ioutil.TempDir("", "astparser-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cache := newFileCache(Config{CacheDir: dir})
+	cache.put("a.go", []byte("package a"), ParsedFile{Package: "a"})
+
+	if f, ok := cache.get("a.go", []byte("package a")); !ok || f.Package != "a" {
+		t.Errorf("get() = %+v, %v", f, ok)
+	}
+	if _, ok := cache.get("a.go", []byte("package b")); ok {
+		t.Errorf("changed content must not hit the cache")
+	}
+	if _, ok := cache.get("b.go", []byte("package a")); ok {
+		t.Errorf("other file must not hit the cache")
+	}
+}
+
+func TestFileCache_fingerprint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "astparser-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	newFileCache(Config{CacheDir: dir}).put("a.go", []byte("package a"), ParsedFile{Package: "a"})
+
+	for _, cfg := range []Config{
+		{CacheDir: dir, SkipGenerated: true},
+		{CacheDir: dir, IncludeTests: true},
+		{CacheDir: dir, IncludeExternalTests: true},
+		{CacheDir: dir, GOOS: "windows"},
+		{CacheDir: dir, BuildTags: []string{"integration"}},
+	} {
+		if _, ok := newFileCache(cfg).get("a.go", []byte("package a")); ok {
+			t.Errorf("config %+v must not hit the cache", cfg)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package astparser
+
+import (
+	"reflect"
+	"sort"
+)
+
+// ChangeKind is a kind of declaration change.
+type ChangeKind int
+
+const (
+	ChangeAdded ChangeKind = iota + 1
+	ChangeRemoved
+	ChangeModified
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeModified:
+		return "modified"
+	default:
+		return "unknown"
+	}
+}
+
+// Change describes a single changed declaration.
+type Change struct {
+	Kind ChangeKind
+	// Package is the declaration package import path or its name
+	// if the import path is unknown.
+	Package string
+	// Struct is the owning struct name of a changed field.
+	Struct string
+	// Name is a declaration name. Embedded fields are named by their type, like `*Dep`.
+	Name string
+	// File is the declaration file, the previous one for removed declarations.
+	File string
+}
+
+// ChangeSet contains declarations changed between two loads sorted by package and name.
+type ChangeSet struct {
+	Structs   []Change
+	Constants []Change
+	Fields    []Change
+}
+
+// Empty reports whether nothing has changed.
+func (c ChangeSet) Empty() bool {
+	return len(c.Structs) == 0 && len(c.Constants) == 0 && len(c.Fields) == 0
+}
+
+type declKey struct {
+	pkg  string
+	name string
+}
+
+// diffPackages compares structs, their fields and constants of two loads.
+func diffPackages(before, after []*Package) ChangeSet {
+	oldStructs, oldConstants := indexDecls(before)
+	newStructs, newConstants := indexDecls(after)
+
+	var changes ChangeSet
+	for key, s := range newStructs {
+		prev, ok := oldStructs[key]
+		if !ok {
+			changes.Structs = append(changes.Structs, Change{Kind: ChangeAdded, Package: key.pkg, Name: key.name, File: s.File})
+			for _, f := range s.Fields {
+				changes.Fields = append(changes.Fields, Change{Kind: ChangeAdded, Package: key.pkg, Struct: s.Name, Name: fieldKey(f), File: s.File})
+			}
+			continue
+		}
+
+		fieldChanges := diffFields(key.pkg, prev, s)
+		changes.Fields = append(changes.Fields, fieldChanges...)
+		if len(fieldChanges) > 0 || !reflect.DeepEqual(prev.Comments, s.Comments) {
+			changes.Structs = append(changes.Structs, Change{Kind: ChangeModified, Package: key.pkg, Name: key.name, File: s.File})
+		}
+	}
+	for key, s := range oldStructs {
+		if _, ok := newStructs[key]; ok {
+			continue
+		}
+		changes.Structs = append(changes.Structs, Change{Kind: ChangeRemoved, Package: key.pkg, Name: key.name, File: s.File})
+		for _, f := range s.Fields {
+			changes.Fields = append(changes.Fields, Change{Kind: ChangeRemoved, Package: key.pkg, Struct: s.Name, Name: fieldKey(f), File: s.File})
+		}
+	}
+
+	for key, c := range newConstants {
+		prev, ok := oldConstants[key]
+		switch {
+		case !ok:
+			changes.Constants = append(changes.Constants, Change{Kind: ChangeAdded, Package: key.pkg, Name: key.name, File: c.File})
+		case prev.Value != c.Value:
+			changes.Constants = append(changes.Constants, Change{Kind: ChangeModified, Package: key.pkg, Name: key.name, File: c.File})
+		}
+	}
+	for key, c := range oldConstants {
+		if _, ok := newConstants[key]; !ok {
+			changes.Constants = append(changes.Constants, Change{Kind: ChangeRemoved, Package: key.pkg, Name: key.name, File: c.File})
+		}
+	}
+
+	sortChanges(changes.Structs)
+	sortChanges(changes.Constants)
+	sortChanges(changes.Fields)
+	return changes
+}
+
+func diffFields(pkg string, before, after StructDef) []Change {
+	oldFields := make(map[string]FieldDef, len(before.Fields))
+	for _, f := range before.Fields {
+		oldFields[fieldKey(f)] = f
+	}
+
+	var changes []Change
+	seen := make(map[string]bool, len(after.Fields))
+	for _, f := range after.Fields {
+		key := fieldKey(f)
+		seen[key] = true
+		prev, ok := oldFields[key]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Kind: ChangeAdded, Package: pkg, Struct: after.Name, Name: key, File: after.File})
+		case !equalField(prev, f):
+			changes = append(changes, Change{Kind: ChangeModified, Package: pkg, Struct: after.Name, Name: key, File: after.File})
+		}
+	}
+	for _, f := range before.Fields {
+		if key := fieldKey(f); !seen[key] {
+			changes = append(changes, Change{Kind: ChangeRemoved, Package: pkg, Struct: before.Name, Name: key, File: before.File})
+		}
+	}
+	return changes
+}
+
+func indexDecls(packages []*Package) (map[declKey]StructDef, map[declKey]ConstantDef) {
+	structs := map[declKey]StructDef{}
+	constants := map[declKey]ConstantDef{}
+	for _, p := range packages {
+		pkg := p.ImportPath
+		if pkg == "" {
+			pkg = p.Name
+		}
+		for _, s := range p.Structs {
+			structs[declKey{pkg: pkg, name: s.Name}] = s
+		}
+		for _, c := range p.Constants {
+			constants[declKey{pkg: pkg, name: c.Name}] = c
+		}
+	}
+	return structs, constants
+}
+
+func sortChanges(changes []Change) {
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		if a.Struct != b.Struct {
+			return a.Struct < b.Struct
+		}
+		return a.Name < b.Name
+	})
+}
+
+// fieldKey identifies the field in a struct, embedded fields are identified by type.
+func fieldKey(f FieldDef) string {
+	if f.FieldName != "" {
+		return f.FieldName
+	}
+	return typeString(f.FieldType)
+}
+
+func equalField(a, b FieldDef) bool {
+	return a.FieldName == b.FieldName &&
+		a.CompositionField == b.CompositionField &&
+		a.JsonName == b.JsonName &&
+		a.Nullable == b.Nullable &&
+		reflect.DeepEqual(a.Comments, b.Comments) &&
+		reflect.DeepEqual(a.AllTags, b.AllTags) &&
+		equalTypes(a.FieldType, b.FieldType)
+}
@@ -0,0 +1,97 @@
+package astparser
+
+import (
+	"sort"
+)
+
+// Closure is a pruned model of the types transitively referenced by root types.
+type Closure struct {
+	// Roots are IDs of the root types.
+	Roots []string
+	// Packages contain only the referenced structs, named types and constants
+	// of the referenced named types (enums). Functions are dropped, so are
+	// files without referenced declarations.
+	Packages []*Package
+	// Unresolved are references to the types which are not loaded,
+	// like `time.Time` or types of files filtered out.
+	Unresolved []UnresolvedRef
+}
+
+// UnresolvedRef is a reference to a type which is not loaded.
+type UnresolvedRef struct {
+	// From is the ID of the referencing type.
+	From string
+	// Type is the ID of the referenced type.
+	Type string
+}
+
+// ExtractClosure returns the closure of root types. Roots are type IDs like
+// `example.com/app/models.Event` or type names if they are unique among packages.
+func ExtractClosure(packages []*Package, roots ...string) (*Closure, error) {
+	g := NewTypeGraph(packages)
+
+	c := &Closure{}
+	for _, root := range roots {
+		id, err := g.Find(root)
+		if err != nil {
+			return nil, err
+		}
+		c.Roots = append(c.Roots, id)
+	}
+
+	reachable := map[string]bool{}
+	for _, id := range g.Reachable(c.Roots...) {
+		reachable[id] = true
+		for _, u := range g.Unresolved(id) {
+			c.Unresolved = append(c.Unresolved, UnresolvedRef{From: id, Type: u})
+		}
+	}
+	sort.Slice(c.Unresolved, func(i, j int) bool {
+		if c.Unresolved[i].From != c.Unresolved[j].From {
+			return c.Unresolved[i].From < c.Unresolved[j].From
+		}
+		return c.Unresolved[i].Type < c.Unresolved[j].Type
+	})
+
+	for _, p := range packages {
+		if pruned := prunePackage(p, reachable); pruned != nil {
+			c.Packages = append(c.Packages, pruned)
+		}
+	}
+	return c, nil
+}
+
+// prunePackage returns the package with reachable declarations only, nil if there are none.
+func prunePackage(p *Package, reachable map[string]bool) *Package {
+	pkg := packageID(p)
+	pruned := &Package{Name: p.Name, ImportPath: p.ImportPath, Dir: p.Dir, Test: p.Test, Doc: p.Doc}
+	for _, f := range p.Files {
+		file := f
+		file.Structs, file.Types, file.Constants, file.Funcs = nil, nil, nil, nil
+		for _, s := range f.Structs {
+			if reachable[pkg+"."+s.Name] {
+				file.Structs = append(file.Structs, s)
+			}
+		}
+		for _, t := range f.Types {
+			if reachable[pkg+"."+t.Name] {
+				file.Types = append(file.Types, t)
+			}
+		}
+		for _, c := range f.Constants {
+			if c.Type != "" && reachable[pkg+"."+c.Type] {
+				file.Constants = append(file.Constants, c)
+			}
+		}
+
+		if len(file.Structs)+len(file.Types)+len(file.Constants) > 0 {
+			pruned.addFile(file)
+		}
+	}
+
+	if len(pruned.Files) == 0 {
+		return nil
+	}
+	pruned.buildIndex()
+	return pruned
+}
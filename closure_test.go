@@ -0,0 +1,96 @@
+package astparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractClosure(t *testing.T) {
+	sources := map[string][]byte{
+		"models/enum.go": []byte(`package models
+
+const (
+	EventCreated EventID = "created"
+	EventDeleted EventID = "deleted"
+	Untyped              = "untyped"
+	LabelNew     Label   = "new"
+)
+
+func NewEvent() Event { return Event{} }
+`),
+	}
+	for name, data := range graphSources {
+		sources[name] = data
+	}
+	packages, err := LoadPackages(Config{InputDirs: []string{"models", "dto"}, Sources: sources})
+	if err != nil {
+		t.Fatalf("LoadPackages() error = %v", err)
+	}
+
+	c, err := ExtractClosure(packages, "Event")
+	if err != nil {
+		t.Fatalf("ExtractClosure() error = %v", err)
+	}
+
+	const models = "example.com/app/models."
+	if want := []string{models + "Event"}; !reflect.DeepEqual(c.Roots, want) {
+		t.Errorf("Roots\nhave %+v, \nwant %+v", c.Roots, want)
+	}
+	if len(c.Packages) != 1 {
+		t.Fatalf("expected only models package, got %d packages", len(c.Packages))
+	}
+
+	p := c.Packages[0]
+	var structs, types, constants []string
+	for _, s := range p.Structs {
+		structs = append(structs, s.Name)
+	}
+	for _, t := range p.Types {
+		types = append(types, t.Name)
+	}
+	for _, c := range p.Constants {
+		constants = append(constants, c.Name)
+	}
+	if want := []string{"Event", "User", "Address", "Owner", "Meta"}; !reflect.DeepEqual(structs, want) {
+		t.Errorf("structs\nhave %+v, \nwant %+v", structs, want)
+	}
+	if want := []string{"EventID", "Tag", "Label"}; !reflect.DeepEqual(types, want) {
+		t.Errorf("types\nhave %+v, \nwant %+v", types, want)
+	}
+	if want := []string{"EventCreated", "EventDeleted", "LabelNew"}; !reflect.DeepEqual(constants, want) {
+		t.Errorf("constants\nhave %+v, \nwant %+v", constants, want)
+	}
+	if len(p.Funcs) != 0 {
+		t.Errorf("unexpected funcs %+v", p.Funcs)
+	}
+	if _, ok := p.Lookup("Event"); !ok {
+		t.Error("Event is not found in pruned package")
+	}
+
+	wantUnresolved := []UnresolvedRef{
+		{From: models + "Event", Type: "time.Time"},
+		{From: models + "Meta", Type: models + "Unknown"},
+	}
+	if !reflect.DeepEqual(c.Unresolved, wantUnresolved) {
+		t.Errorf("Unresolved\nhave %+v, \nwant %+v", c.Unresolved, wantUnresolved)
+	}
+}
+
+func TestExtractClosure_roots(t *testing.T) {
+	packages, err := LoadPackages(Config{InputDirs: []string{"models", "dto"}, Sources: graphSources})
+	if err != nil {
+		t.Fatalf("LoadPackages() error = %v", err)
+	}
+
+	c, err := ExtractClosure(packages, "example.com/app/dto.Item")
+	if err != nil {
+		t.Fatalf("ExtractClosure() error = %v", err)
+	}
+	if len(c.Packages) != 2 || len(c.Packages[0].Structs) != 1 || len(c.Packages[1].Types) != 2 {
+		t.Errorf("unexpected closure %+v", c.Packages)
+	}
+
+	if _, err := ExtractClosure(packages, "Missing"); err == nil {
+		t.Error("expected error for unknown root")
+	}
+}
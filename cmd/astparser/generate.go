@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mkorolyov/astparser"
+	"github.com/mkorolyov/astparser/gen/jsonschema"
+	"github.com/mkorolyov/astparser/gen/openapi"
+	"github.com/mkorolyov/astparser/gen/proto"
+	"github.com/mkorolyov/astparser/gen/typescript"
+)
+
+// generator writes code of the target types to the target output.
+type generator func(model *astparser.Model, target astparser.Target) error
+
+var generators = map[string]generator{
+	"jsonschema": generateJSONSchema,
+	"openapi":    generateOpenAPI,
+	"proto":      generateProto,
+	"typescript": generateTypeScript,
+}
+
+func runGenerate(args []string, stdout, stderr io.Writer) error {
+	flags := newFlagSet("generate", "[flags] [dir | dir/...]...", stderr)
+	loadFlags := addLoadFlags(flags)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	cfg, err := loadFlags.config(flags, flags.Args())
+	if err != nil {
+		return err
+	}
+	if len(cfg.Targets) == 0 {
+		return errors.New("no generate targets in the config file")
+	}
+	for i, t := range cfg.Targets {
+		if _, ok := generators[t.Generator]; !ok {
+			return fmt.Errorf("generate[%d].generator: unknown generator %q", i, t.Generator)
+		}
+	}
+
+	packages, err := astparser.LoadPackages(cfg)
+	if err != nil {
+		return err
+	}
+	model := astparser.NewModel(packages)
+	for _, t := range cfg.Targets {
+		if err := generators[t.Generator](model, t); err != nil {
+			return fmt.Errorf("%s: %v", t.Generator, err)
+		}
+		fmt.Fprintf(stdout, "%s: %s\n", t.Generator, t.Output)
+	}
+	return nil
+}
+
+// targetRoots returns IDs of the target types, all the structs if types are not set.
+func targetRoots(model *astparser.Model, t astparser.Target) ([]string, error) {
+	if len(t.Types) == 0 {
+		var ids []string
+		for _, n := range model.Graph().Nodes() {
+			if n.Struct != nil {
+				ids = append(ids, n.ID)
+			}
+		}
+		return ids, nil
+	}
+
+	ids := make([]string, len(t.Types))
+	for i, name := range t.Types {
+		id, err := model.Graph().Find(name)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// generateJSONSchema writes the schema document of each target type
+// to `<output>/<name>.schema.json`.
+func generateJSONSchema(model *astparser.Model, t astparser.Target) error {
+	roots, err := targetRoots(model, t)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(t.Output, 0755); err != nil {
+		return err
+	}
+
+	g := jsonschema.New(model, jsonschema.Options{})
+	for _, id := range roots {
+		s, err := g.Schema(id)
+		if err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(s, "", "  ")
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(t.Output, g.Name(id)+".schema.json")
+		if err := ioutil.WriteFile(path, append(data, '\n'), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generateOpenAPI writes the OpenAPI document with component schemas of the target types
+// to the output file, as YAML if the file extension is `.yaml` or `.yml`.
+// The `title` and `version` options set the document info.
+func generateOpenAPI(model *astparser.Model, t astparser.Target) error {
+	roots, err := targetRoots(model, t)
+	if err != nil {
+		return err
+	}
+	info := openapi.Info{Title: t.Options["title"], Version: t.Options["version"]}
+	doc, err := openapi.New(model, openapi.Options{Info: info}).Document(roots...)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	format := "json"
+	if ext := strings.ToLower(filepath.Ext(t.Output)); ext == ".yaml" || ext == ".yml" {
+		format = "yaml"
+	}
+	return writeFile(t.Output, func(w io.Writer) error { return encode(w, format, data) })
+}
+
+// writeFile creates the file and its dir and writes it.
+func writeFile(path string, write func(w io.Writer) error) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// generateTypeScript writes declarations of the target types to the output `.d.ts` file.
+// Options like `type:github.com/google/uuid.UUID: string` map types to TypeScript ones.
+func generateTypeScript(model *astparser.Model, t astparser.Target) error {
+	roots, err := targetRoots(model, t)
+	if err != nil {
+		return err
+	}
+	opts := typescript.Options{Types: map[string]string{}}
+	for k, v := range t.Options {
+		if strings.HasPrefix(k, "type:") {
+			opts.Types[strings.TrimPrefix(k, "type:")] = v
+		}
+	}
+
+	g := typescript.New(model, opts)
+	return writeFile(t.Output, func(w io.Writer) error { return g.Generate(w, roots...) })
+}
+
+// generateProto writes proto3 messages and enums of the target types to the output `.proto` file.
+// The `package` and `go_package` options set the proto package and the go_package option,
+// options like `type:github.com/google/uuid.UUID: string` map types to proto ones.
+// Field numbers are kept in the lock file, `<output>.lock` unless the `lock` option
+// sets the path relative to the output dir.
+func generateProto(model *astparser.Model, t astparser.Target) error {
+	roots, err := targetRoots(model, t)
+	if err != nil {
+		return err
+	}
+	lockPath := t.Output + ".lock"
+	if p := t.Options["lock"]; p != "" {
+		lockPath = p
+		if !filepath.IsAbs(p) {
+			lockPath = filepath.Join(filepath.Dir(t.Output), filepath.FromSlash(p))
+		}
+	}
+	lock, err := proto.ReadLock(lockPath)
+	if err != nil {
+		return err
+	}
+
+	opts := proto.Options{
+		Package:   t.Options["package"],
+		GoPackage: t.Options["go_package"],
+		Types:     map[string]string{},
+		Lock:      lock,
+	}
+	for k, v := range t.Options {
+		if strings.HasPrefix(k, "type:") {
+			opts.Types[strings.TrimPrefix(k, "type:")] = v
+		}
+	}
+
+	g := proto.New(model, opts)
+	if err := writeFile(t.Output, func(w io.Writer) error { return g.Generate(w, roots...) }); err != nil {
+		return err
+	}
+	return lock.WriteFile(lockPath)
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// writeFiles writes files by slash separated path to a temporary dir.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "astparser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+var generateFiles = map[string]string{
+	"models/event.go": `package models
+
+import "time"
+
+// Event is a user action.
+type Event struct {
+	ID   string    ` + "`json:\"id\"`" + `
+	Kind Kind      ` + "`json:\"kind\"`" + `
+	At   time.Time ` + "`json:\"at,omitempty\"`" + `
+}
+
+type Kind string
+
+const (
+	KindCreated Kind = "created"
+	KindDeleted Kind = "deleted"
+)
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+`,
+}
+
+func Test_runGenerate(t *testing.T) {
+	dir := writeFiles(t, generateFiles)
+	config := filepath.Join(dir, ".astparser.yaml")
+	err := ioutil.WriteFile(config, []byte(`inputs: [models]
+generate:
+  - generator: jsonschema
+    output: schemas
+    types: [Event]
+  - generator: openapi
+    output: api/openapi.yaml
+    options:
+      title: Events
+  - generator: typescript
+    output: web/models.d.ts
+    types: [Event]
+    options:
+      "type:time.Time": Date
+  - generator: proto
+    output: proto/events.proto
+    types: [Event]
+    options:
+      package: events.v1
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"generate", "-config", config}, &stdout, &stderr); code != 0 {
+		t.Fatalf("run() = %d, stderr %s", code, stderr.String())
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "schemas", "Event.schema.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema struct {
+		Ref  string                     `json:"$ref"`
+		Defs map[string]json.RawMessage `json:"$defs"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("failed to decode schema: %v\n%s", err, data)
+	}
+	if schema.Ref != "#/$defs/Event" || len(schema.Defs) != 2 {
+		t.Errorf("unexpected schema\n%s", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "schemas", "User.schema.json")); !os.IsNotExist(err) {
+		t.Errorf("unexpected User schema, stat error %v", err)
+	}
+
+	data, err = ioutil.ReadFile(filepath.Join(dir, "api", "openapi.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		OpenAPI string `yaml:"openapi"`
+		Info    struct {
+			Title string `yaml:"title"`
+		} `yaml:"info"`
+		Components struct {
+			Schemas map[string]interface{} `yaml:"schemas"`
+		} `yaml:"components"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("failed to decode document: %v\n%s", err, data)
+	}
+	if doc.OpenAPI != "3.1.0" || doc.Info.Title != "Events" || len(doc.Components.Schemas) != 3 {
+		t.Errorf("unexpected document\n%s", data)
+	}
+
+	data, err = ioutil.ReadFile(filepath.Join(dir, "web", "models.d.ts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ts := string(data); !strings.Contains(ts, "at?: Date;") || !strings.Contains(ts, `export type Kind = "created" | "deleted";`) {
+		t.Errorf("unexpected declarations\n%s", data)
+	}
+
+	data, err = ioutil.ReadFile(filepath.Join(dir, "proto", "events.proto"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := string(data); !strings.Contains(p, "package events.v1;") || !strings.Contains(p, "  google.protobuf.Timestamp at = 3;") {
+		t.Errorf("unexpected proto\n%s", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "proto", "events.proto.lock")); err != nil {
+		t.Errorf("lock file is not written: %v", err)
+	}
+}
+
+func Test_runGenerateErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{name: "no targets", config: "inputs: [models]\n", wantErr: "no generate targets"},
+		{
+			name:    "unknown generator",
+			config:  "inputs: [models]\ngenerate:\n  - generator: cobol\n    output: out\n",
+			wantErr: `generate[0].generator: unknown generator "cobol"`,
+		},
+		{
+			name:    "unknown type",
+			config:  "inputs: [models]\ngenerate:\n  - generator: jsonschema\n    output: out\n    types: [Missing]\n",
+			wantErr: "jsonschema: type Missing not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFiles(t, generateFiles)
+			config := filepath.Join(dir, ".astparser.yaml")
+			if err := ioutil.WriteFile(config, []byte(tt.config), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			var stdout, stderr bytes.Buffer
+			if code := run([]string{"generate", "-config", config}, &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), tt.wantErr) {
+				t.Errorf("run() = %d, stderr %q, want 1 and containing %q", code, stderr.String(), tt.wantErr)
+			}
+		})
+	}
+}
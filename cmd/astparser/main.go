@@ -0,0 +1,277 @@
+// Command astparser loads go files and dumps parsed declarations
+// as JSON or YAML, so non-Go tools could consume them. The output
+// is the model encoding of astparser.MarshalModel. The query command
+// prints types or fields matching a query as a table or JSON, the generate
+// command runs generators of the config file targets.
+//
+// Usage:
+//
+//	astparser [dump] [flags] [dir | dir/...]...
+//	astparser query [flags] 'structs where tag(db) and field.type == time.Time' [dir | dir/...]...
+//	astparser generate [flags] [dir | dir/...]...
+//
+// Dirs ending with `/...` are loaded recursively. Without dirs the
+// `.astparser.yaml` config file found in the working dir or its parents
+// is used, or the working dir if there is none. Flags override the config file.
+//
+// The exit code is 2 for invalid flags or arguments and 1 for load
+// and generate failures.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mkorolyov/astparser"
+	"gopkg.in/yaml.v3"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// command is a CLI subcommand.
+type command struct {
+	name string
+	run  func(args []string, stdout, stderr io.Writer) error
+}
+
+var commands = []command{
+	{name: "dump", run: runDump},
+	{name: "query", run: runQuery},
+	{name: "generate", run: runGenerate},
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	cmd := commands[0]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		for _, c := range commands {
+			if c.name == args[0] {
+				cmd, args = c, args[1:]
+				break
+			}
+		}
+	}
+
+	err := cmd.run(args, stdout, stderr)
+	if err == nil {
+		return 0
+	}
+	var usage usageError
+	isUsage := errors.As(err, &usage)
+	if !isUsage || usage.err != flag.ErrHelp {
+		fmt.Fprintf(stderr, "astparser %s: %v\n", cmd.name, err)
+	}
+	if isUsage {
+		return 2
+	}
+	return 1
+}
+
+// usageError is an invalid command line, run exits with code 2 on it.
+type usageError struct {
+	err error
+}
+
+func (e usageError) Error() string {
+	return e.err.Error()
+}
+
+// parseFlags parses the command flags, errors are usage errors.
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	if err := flags.Parse(args); err != nil {
+		return usageError{err}
+	}
+	return nil
+}
+
+// newFlagSet returns the command flag set printing errors and usage to stderr.
+func newFlagSet(name, usage string, stderr io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "usage: astparser %s %s\n", name, usage)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+func runDump(args []string, stdout, stderr io.Writer) error {
+	flags := newFlagSet("dump", "[flags] [dir | dir/...]...", stderr)
+	format := flags.String("format", "json", "output format: json or yaml")
+	loadFlags := addLoadFlags(flags)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if *format != "json" && *format != "yaml" {
+		return usageError{fmt.Errorf("unknown format %q, want json or yaml", *format)}
+	}
+
+	cfg, err := loadFlags.config(flags, flags.Args())
+	if err != nil {
+		return err
+	}
+
+	packages, err := astparser.LoadPackages(cfg)
+	if err != nil {
+		return err
+	}
+	data, err := astparser.MarshalModel(packages)
+	if err != nil {
+		return err
+	}
+	return encode(stdout, *format, data)
+}
+
+// loadFlags are flags mirroring astparser.Config.
+type loadFlags struct {
+	configFile      string
+	include         string
+	exclude         string
+	patterns        stringsFlag
+	excludePatterns stringsFlag
+	recursive       bool
+	goos            string
+	goarch          string
+	tags            string
+	tests           bool
+	externalTests   bool
+	skipGenerated   bool
+}
+
+func addLoadFlags(flags *flag.FlagSet) *loadFlags {
+	f := &loadFlags{}
+	flags.StringVar(&f.configFile, "config", "", "config file, looked up in the working dir and its parents if not set")
+	flags.StringVar(&f.include, "include", "", "regexp of file paths relative to the input dir to load")
+	flags.StringVar(&f.exclude, "exclude", "", "regexp of file paths relative to the input dir to skip")
+	flags.Var(&f.patterns, "pattern", "glob of file paths to load like `**/event_*.go`, could be repeated")
+	flags.Var(&f.excludePatterns, "exclude-pattern", "glob of file paths to skip, could be repeated")
+	flags.BoolVar(&f.recursive, "recursive", false, "load subdirectories")
+	flags.StringVar(&f.goos, "goos", "", "GOOS files are matched against")
+	flags.StringVar(&f.goarch, "goarch", "", "GOARCH files are matched against")
+	flags.StringVar(&f.tags, "tags", "", "comma separated build tags")
+	flags.BoolVar(&f.tests, "tests", false, "load _test.go files of the package itself")
+	flags.BoolVar(&f.externalTests, "external-tests", false, "load _test.go files of the external _test package")
+	flags.BoolVar(&f.skipGenerated, "skip-generated", false, "skip generated files")
+	return f
+}
+
+// config builds Config from the config file, flags set explicitly and dir arguments.
+func (f *loadFlags) config(flags *flag.FlagSet, dirs []string) (astparser.Config, error) {
+	path := f.configFile
+	if path == "" {
+		var err error
+		if path, err = astparser.FindConfigFile("."); err != nil {
+			return astparser.Config{}, err
+		}
+	}
+
+	var cfg astparser.Config
+	if path != "" {
+		var err error
+		if cfg, err = astparser.LoadConfigFile(path); err != nil {
+			return astparser.Config{}, err
+		}
+	}
+
+	flags.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "include":
+			cfg.IncludeRegexp = f.include
+		case "exclude":
+			cfg.ExcludeRegexp = f.exclude
+		case "pattern":
+			cfg.Patterns = f.patterns
+		case "exclude-pattern":
+			cfg.ExcludePatterns = f.excludePatterns
+		case "recursive":
+			cfg.Recursive = f.recursive
+		case "goos":
+			cfg.GOOS = f.goos
+		case "goarch":
+			cfg.GOARCH = f.goarch
+		case "tags":
+			cfg.BuildTags = strings.FieldsFunc(f.tags, func(r rune) bool { return r == ',' || r == ' ' })
+		case "tests":
+			cfg.IncludeTests = f.tests
+		case "external-tests":
+			cfg.IncludeExternalTests = f.externalTests
+		case "skip-generated":
+			cfg.SkipGenerated = f.skipGenerated
+		}
+	})
+
+	if len(dirs) > 0 {
+		cfg.InputDir, cfg.InputDirs = "", nil
+		for _, arg := range dirs {
+			dir := arg
+			if strings.HasSuffix(arg, "/...") || arg == "..." {
+				dir = strings.TrimSuffix(strings.TrimSuffix(arg, "..."), "/")
+				if dir == "" {
+					dir = "."
+				}
+				cfg.Recursive = true
+			}
+			cfg.InputDirs = append(cfg.InputDirs, dir)
+		}
+	}
+
+	return cfg, nil
+}
+
+// encode writes JSON data to w indented or converted to YAML.
+func encode(w io.Writer, format string, data []byte) error {
+	switch format {
+	case "json":
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, data, "", "  "); err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+		_, err := w.Write(buf.Bytes())
+		return err
+	case "yaml":
+		// JSON is YAML, decoding to a node keeps keys order.
+		var node yaml.Node
+		if err := yaml.Unmarshal(data, &node); err != nil {
+			return err
+		}
+		resetStyle(&node)
+		var buf bytes.Buffer
+		e := yaml.NewEncoder(&buf)
+		e.SetIndent(2)
+		if err := e.Encode(&node); err != nil {
+			return err
+		}
+		_, err := w.Write(buf.Bytes())
+		return err
+	default:
+		return fmt.Errorf("unknown format %q, want json or yaml", format)
+	}
+}
+
+// resetStyle drops JSON flow and quoting styles so the node is encoded in block YAML style.
+func resetStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, n := range node.Content {
+		resetStyle(n)
+	}
+}
+
+// stringsFlag is a repeated string flag.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mkorolyov/astparser"
+	"gopkg.in/yaml.v3"
+)
+
+func Test_runDump(t *testing.T) {
+	for _, format := range []string{"json", "yaml"} {
+		t.Run(format, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run([]string{"dump", "-format", format, "-include", "^constants", "../../fixtures_test"}, &stdout, &stderr)
+			if code != 0 {
+				t.Fatalf("run() = %d, stderr %s", code, stderr.String())
+			}
+
+			data := stdout.Bytes()
+			if format == "yaml" {
+				var v interface{}
+				if err := yaml.Unmarshal(data, &v); err != nil {
+					t.Fatalf("failed to decode output: %v\n%s", err, data)
+				}
+				var err error
+				if data, err = json.Marshal(v); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			packages, err := astparser.UnmarshalModel(data)
+			if err != nil {
+				t.Fatalf("failed to decode output: %v\n%s", err, data)
+			}
+			if len(packages) != 1 || packages[0].Name != "fixtures_test" || len(packages[0].Constants) != 12 {
+				t.Errorf("unexpected output %+v", packages)
+			}
+		})
+	}
+}
+
+func Test_runErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		wantErr  string
+	}{
+		{name: "format", args: []string{"-format", "xml", "../../fixtures_test"}, wantCode: 2, wantErr: `unknown format "xml"`},
+		{name: "flag", args: []string{"-unknown"}, wantCode: 2, wantErr: "flag provided but not defined"},
+		{name: "query", args: []string{"query"}, wantCode: 2, wantErr: "query is not set"},
+		{name: "dir", args: []string{"../../missing"}, wantCode: 1, wantErr: "missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(tt.args, &stdout, &stderr); code != tt.wantCode || !strings.Contains(stderr.String(), tt.wantErr) {
+				t.Errorf("run() = %d, stderr %q, want %d and containing %q", code, stderr.String(), tt.wantCode, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_loadFlags(t *testing.T) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	f := addLoadFlags(flags)
+	err := flags.Parse([]string{"-tags", "a,b", "-pattern", "event_*.go", "-pattern", "user.go", "-tests", "models/...", "dto"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := f.config(flags, flags.Args())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cfg.InputDirs, []string{"models", "dto"}) || !cfg.Recursive {
+		t.Errorf("unexpected inputs %q, recursive %v", cfg.InputDirs, cfg.Recursive)
+	}
+	if !reflect.DeepEqual(cfg.BuildTags, []string{"a", "b"}) || !cfg.IncludeTests {
+		t.Errorf("unexpected tags %q, tests %v", cfg.BuildTags, cfg.IncludeTests)
+	}
+	if !reflect.DeepEqual(cfg.Patterns, []string{"event_*.go", "user.go"}) {
+		t.Errorf("unexpected patterns %q", cfg.Patterns)
+	}
+}
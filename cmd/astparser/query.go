@@ -0,0 +1,534 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+
+	"github.com/mkorolyov/astparser"
+)
+
+const queryUsage = `[flags] query [dir | dir/...]...
+
+Query is a subject optionally followed by conditions:
+
+	structs | types | fields [where condition]
+
+Conditions are combined with and, or, not and parentheses:
+
+	tag(key) [op value]         a field has the tag, optionally with the value
+	annotation(key) [op value]  the type doc has the +key or +key=value line
+	implements(iface)           the type has all the interface methods
+	name, package, kind, file   the type attributes
+	field.name, field.type      the field attributes
+
+Operators are ==, != and =~ for regexps, values with spaces should be quoted.
+Field conditions of structs and types match if any field matches, e.g.
+
+	structs where tag(db) and field.type == time.Time
+`
+
+func runQuery(args []string, stdout, stderr io.Writer) error {
+	flags := newFlagSet("query", queryUsage, stderr)
+	format := flags.String("format", "table", "output format: table or json")
+	loadFlags := addLoadFlags(flags)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return usageError{errors.New("query is not set")}
+	}
+	if *format != "table" && *format != "json" {
+		return usageError{fmt.Errorf("unknown format %q, want table or json", *format)}
+	}
+
+	cfg, err := loadFlags.config(flags, flags.Args()[1:])
+	if err != nil {
+		return err
+	}
+	packages, err := astparser.LoadPackages(cfg)
+	if err != nil {
+		return err
+	}
+
+	model := astparser.NewModel(packages)
+	q, err := parseQuery(flags.Arg(0), model)
+	if err != nil {
+		return usageError{err}
+	}
+
+	results := q.run(model)
+	if *format == "json" {
+		if results == nil {
+			results = []queryResult{}
+		}
+		data, err := json.Marshal(results)
+		if err != nil {
+			return err
+		}
+		return encode(stdout, "json", data)
+	}
+	return writeTable(stdout, q.subject, results)
+}
+
+// queryResult is a type or a field matched by a query.
+type queryResult struct {
+	node  *astparser.TypeNode
+	field *astparser.FieldDef
+}
+
+func (r queryResult) kind() string {
+	if r.node.Struct != nil {
+		return "struct"
+	}
+	if r.node.Type.Type == nil {
+		return ""
+	}
+	return r.node.Type.Type.Kind().String()
+}
+
+func (r queryResult) MarshalJSON() ([]byte, error) {
+	v := struct {
+		ID      string            `json:"id"`
+		Name    string            `json:"name"`
+		Package string            `json:"package"`
+		Kind    string            `json:"kind,omitempty"`
+		File    string            `json:"file"`
+		Field   string            `json:"field,omitempty"`
+		Type    string            `json:"type,omitempty"`
+		Tags    map[string]string `json:"tags,omitempty"`
+	}{
+		ID:      r.node.ID,
+		Name:    r.node.Name,
+		Package: r.node.Package.Name,
+		File:    r.node.File(),
+	}
+	if r.field == nil {
+		v.Kind = r.kind()
+	} else {
+		v.Field, v.Type, v.Tags = r.field.FieldName, fieldType(r.field), r.field.AllTags
+	}
+	return json.Marshal(v)
+}
+
+func writeTable(w io.Writer, subject string, results []queryResult) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	if subject == "fields" {
+		fmt.Fprintln(tw, "STRUCT\tFIELD\tTYPE\tTAGS\tFILE")
+		for _, r := range results {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.node.ID, r.field.FieldName, fieldType(r.field), formatTags(r.field.AllTags), r.node.File())
+		}
+	} else {
+		fmt.Fprintln(tw, "TYPE\tKIND\tFILE")
+		for _, r := range results {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", r.node.ID, r.kind(), r.node.File())
+		}
+	}
+	return tw.Flush()
+}
+
+// formatTags renders tags sorted by key like `db:"id" json:"id"`.
+func formatTags(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + ":" + strconv.Quote(tags[k])
+	}
+	return strings.Join(parts, " ")
+}
+
+func fieldType(f *astparser.FieldDef) string {
+	if f.FieldType == nil {
+		return ""
+	}
+	return f.FieldType.String()
+}
+
+// matcher reports whether a type or a field matches a condition.
+type matcher func(r queryResult) bool
+
+type query struct {
+	subject string
+	where   matcher
+}
+
+// run returns the model types or fields matching the query, sorted by type ID.
+func (q *query) run(model *astparser.Model) []queryResult {
+	var results []queryResult
+	for _, n := range model.Graph().Nodes() {
+		switch q.subject {
+		case "structs", "types":
+			r := queryResult{node: n}
+			if (q.subject == "types" || n.Struct != nil) && q.where(r) {
+				results = append(results, r)
+			}
+		case "fields":
+			if n.Struct == nil {
+				continue
+			}
+			for i := range n.Struct.Fields {
+				r := queryResult{node: n, field: &n.Struct.Fields[i]}
+				if q.where(r) {
+					results = append(results, r)
+				}
+			}
+		}
+	}
+	return results
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenOp
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of query"
+	}
+	return strconv.Quote(t.text)
+}
+
+// tokenize splits the query into words, quoted strings, operators and parentheses.
+// Words are anything else up to a space, so types like `[]*User` need no quoting.
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case unicode.IsSpace(rune(c)):
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+		case strings.HasPrefix(s[i:], "=="), strings.HasPrefix(s[i:], "!="), strings.HasPrefix(s[i:], "=~"):
+			tokens = append(tokens, token{kind: tokenOp, text: s[i : i+2], pos: i})
+			i += 2
+		case c == '"':
+			end := i + 1
+			for end < len(s) && s[end] != '"' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			text, err := strconv.Unquote(s[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at %d: %v", i, err)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text, pos: i})
+			i = end + 1
+		default:
+			end := i
+			for end < len(s) && !unicode.IsSpace(rune(s[end])) && !strings.ContainsRune(`()"=!`, rune(s[end])) {
+				end++
+			}
+			if end == i {
+				return nil, fmt.Errorf("unexpected %q at %d", c, i)
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: s[i:end], pos: i})
+			i = end
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(s)}), nil
+}
+
+type queryParser struct {
+	tokens []token
+	model  *astparser.Model
+}
+
+func (p *queryParser) peek() token {
+	return p.tokens[0]
+}
+
+func (p *queryParser) next() token {
+	t := p.tokens[0]
+	if t.kind != tokenEOF {
+		p.tokens = p.tokens[1:]
+	}
+	return t
+}
+
+func (p *queryParser) expect(kind tokenKind, what string) (token, error) {
+	t := p.next()
+	if t.kind != kind {
+		return t, fmt.Errorf("expected %s, got %s at %d", what, t, t.pos)
+	}
+	return t, nil
+}
+
+// isKeyword reports whether the next token is the keyword.
+func (p *queryParser) isKeyword(keyword string) bool {
+	t := p.peek()
+	return t.kind == tokenWord && t.text == keyword
+}
+
+// parseQuery parses the query against the model, the model resolves interfaces of implements conditions.
+func parseQuery(s string, model *astparser.Model) (*query, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %v", err)
+	}
+	p := &queryParser{tokens: tokens, model: model}
+
+	q, err := p.parseQuery()
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %v", err)
+	}
+	return q, nil
+}
+
+func (p *queryParser) parseQuery() (*query, error) {
+	subject, err := p.expect(tokenWord, "structs, types or fields")
+	if err != nil {
+		return nil, err
+	}
+	switch subject.text {
+	case "structs", "types", "fields":
+	default:
+		return nil, fmt.Errorf("unknown subject %s, want structs, types or fields", subject)
+	}
+
+	q := &query{subject: subject.text, where: func(queryResult) bool { return true }}
+	if p.peek().kind == tokenEOF {
+		return q, nil
+	}
+	if !p.isKeyword("where") {
+		return nil, fmt.Errorf("expected where, got %s at %d", p.peek(), p.peek().pos)
+	}
+	p.next()
+
+	if q.where, err = p.parseOr(); err != nil {
+		return nil, err
+	}
+	if t := p.next(); t.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %s at %d", t, t.pos)
+	}
+	return q, nil
+}
+
+func (p *queryParser) parseOr() (matcher, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(r queryResult) bool { return l(r) || right(r) }
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (matcher, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("and") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(r queryResult) bool { return l(r) && right(r) }
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseUnary() (matcher, error) {
+	switch t := p.peek(); {
+	case p.isKeyword("not"):
+		p.next()
+		m, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(r queryResult) bool { return !m(r) }, nil
+	case t.kind == tokenLParen:
+		p.next()
+		m, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokenRParen, ")"); err != nil {
+			return nil, err
+		}
+		return m, nil
+	default:
+		return p.parseCondition()
+	}
+}
+
+func (p *queryParser) parseCondition() (matcher, error) {
+	name, err := p.expect(tokenWord, "condition")
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind == tokenLParen {
+		return p.parseFunc(name)
+	}
+
+	match, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	switch name.text {
+	case "name":
+		return func(r queryResult) bool { return match(r.node.Name) }, nil
+	case "package":
+		return func(r queryResult) bool { return match(r.node.Package.Name, r.node.Package.ImportPath) }, nil
+	case "kind":
+		return func(r queryResult) bool { return match(r.kind()) }, nil
+	case "file":
+		return func(r queryResult) bool { return match(r.node.File()) }, nil
+	case "field.name":
+		return anyField(func(f *astparser.FieldDef) bool { return match(f.FieldName) }), nil
+	case "field.type":
+		return anyField(func(f *astparser.FieldDef) bool { return match(fieldType(f)) }), nil
+	default:
+		return nil, fmt.Errorf("unknown attribute %s at %d", name, name.pos)
+	}
+}
+
+// parseFunc parses conditions like `tag(db)` or `annotation(table) == users`.
+func (p *queryParser) parseFunc(name token) (matcher, error) {
+	p.next()
+	arg := p.next()
+	if arg.kind != tokenWord && arg.kind != tokenString {
+		return nil, fmt.Errorf("expected %s argument, got %s at %d", name.text, arg, arg.pos)
+	}
+	if _, err := p.expect(tokenRParen, ")"); err != nil {
+		return nil, err
+	}
+	key := arg.text
+
+	// tag and annotation values are optionally compared.
+	value := func(string) bool { return true }
+	if name.text != "implements" && p.peek().kind == tokenOp {
+		match, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		value = func(v string) bool { return match(v) }
+	}
+
+	switch name.text {
+	case "tag":
+		return anyField(func(f *astparser.FieldDef) bool {
+			v, ok := f.AllTags[key]
+			return ok && value(v)
+		}), nil
+	case "annotation":
+		return func(r queryResult) bool {
+			v, ok := r.node.Annotations()[key]
+			return ok && value(v)
+		}, nil
+	case "implements":
+		implementers, err := p.model.Implementers(key)
+		if err != nil {
+			return nil, err
+		}
+		ids := map[string]bool{}
+		for _, i := range implementers {
+			ids[i.Type.ID] = true
+		}
+		return func(r queryResult) bool { return ids[r.node.ID] }, nil
+	default:
+		return nil, fmt.Errorf("unknown function %s at %d", name, name.pos)
+	}
+}
+
+// parseComparison parses an operator and a value. The result reports
+// whether any of the values matches, or none of them is equal for `!=`.
+func (p *queryParser) parseComparison() (func(values ...string) bool, error) {
+	op, err := p.expect(tokenOp, "==, != or =~")
+	if err != nil {
+		return nil, err
+	}
+	value := p.next()
+	if value.kind != tokenWord && value.kind != tokenString {
+		return nil, fmt.Errorf("expected value, got %s at %d", value, value.pos)
+	}
+
+	switch op.text {
+	case "==", "!=":
+		equal := op.text == "=="
+		return func(values ...string) bool {
+			for _, v := range values {
+				if v == value.text {
+					return equal
+				}
+			}
+			return !equal
+		}, nil
+	default:
+		re, err := regexp.Compile(value.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regexp at %d: %v", value.pos, err)
+		}
+		return func(values ...string) bool {
+			for _, v := range values {
+				if re.MatchString(v) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	}
+}
+
+// anyField lifts a field condition: a field matches itself,
+// a struct matches if any of its fields does, other types never match.
+func anyField(match func(f *astparser.FieldDef) bool) matcher {
+	return func(r queryResult) bool {
+		if r.field != nil {
+			return match(r.field)
+		}
+		if r.node.Struct == nil {
+			return false
+		}
+		for i := range r.node.Struct.Fields {
+			if match(&r.node.Struct.Fields[i]) {
+				return true
+			}
+		}
+		return false
+	}
+}
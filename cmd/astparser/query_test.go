@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mkorolyov/astparser"
+)
+
+var querySources = map[string][]byte{
+	"go.mod": []byte("module example.com/app\n"),
+	"models/user.go": []byte(`package models
+
+import "time"
+
+// +table=users
+type User struct {
+	ID        int64     ` + "`db:\"id\" json:\"id\"`" + `
+	Name      string    ` + "`json:\"name\"`" + `
+	CreatedAt time.Time ` + "`db:\"created_at\"`" + `
+}
+
+type Event struct {
+	ID int64     ` + "`db:\"id\"`" + `
+	At time.Time
+}
+
+type Doc struct {
+	At time.Time ` + "`db:\"at\"`" + `
+}
+
+// +enum
+type Status string
+
+type Named interface {
+	Name() string
+}
+
+func (User) Name() string { return "" }
+`),
+}
+
+func loadQueryModel(t *testing.T) *astparser.Model {
+	packages, err := astparser.LoadPackages(astparser.Config{InputDir: "models", Sources: querySources})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return astparser.NewModel(packages)
+}
+
+func Test_parseQuery(t *testing.T) {
+	model := loadQueryModel(t)
+
+	const models = "example.com/app/models."
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "structs", want: []string{"Doc", "Event", "User"}},
+		{query: "types where kind != struct", want: []string{"Named", "Status"}},
+		{query: "structs where tag(db) and field.type == time.Time", want: []string{"Doc", "Event", "User"}},
+		{query: "structs where tag(db) == created_at", want: []string{"User"}},
+		{query: "structs where tag(json) or name =~ ^D", want: []string{"Doc", "User"}},
+		{query: "structs where not (tag(json) or name == Doc)", want: []string{"Event"}},
+		{query: `types where annotation(table) == "users" or annotation(enum)`, want: []string{"Status", "User"}},
+		{query: "types where implements(Named)", want: []string{"User"}},
+		{query: "structs where package == example.com/app/models and file =~ user", want: []string{"Doc", "Event", "User"}},
+		{query: "fields where tag(db) and field.type == time.Time", want: []string{"Doc.At", "User.CreatedAt"}},
+		{query: "fields where field.name == ID and name != User", want: []string{"Event.ID"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := parseQuery(tt.query, model)
+			if err != nil {
+				t.Fatalf("parseQuery() error = %v", err)
+			}
+
+			var got []string
+			for _, r := range q.run(model) {
+				name := strings.TrimPrefix(r.node.ID, models)
+				if r.field != nil {
+					name += "." + r.field.FieldName
+				}
+				got = append(got, name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\nhave %+v, \nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseQueryErrors(t *testing.T) {
+	model := loadQueryModel(t)
+
+	tests := []struct {
+		query   string
+		wantErr string
+	}{
+		{query: "", wantErr: "expected structs, types or fields, got end of query at 0"},
+		{query: "funcs", wantErr: `unknown subject "funcs"`},
+		{query: "structs tag(db)", wantErr: `expected where, got "tag" at 8`},
+		{query: "structs where", wantErr: "expected condition, got end of query at 13"},
+		{query: "structs where size == 1", wantErr: `unknown attribute "size" at 14`},
+		{query: "structs where name = User", wantErr: `unexpected '=' at 19`},
+		{query: "structs where name == ", wantErr: "expected value, got end of query"},
+		{query: "structs where name =~ (", wantErr: `expected value, got "("`},
+		{query: `structs where name =~ "["`, wantErr: "invalid regexp"},
+		{query: "structs where (tag(db)", wantErr: "expected ), got end of query"},
+		{query: "structs where size(db)", wantErr: `unknown function "size"`},
+		{query: "structs where implements(User)", wantErr: "is not an interface"},
+		{query: `structs where name == "User`, wantErr: "unterminated string at 22"},
+		{query: "structs where tag(db) tag(json)", wantErr: `unexpected "tag" at 22`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := parseQuery(tt.query, model)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseQuery() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_runQuery(t *testing.T) {
+	dir, err := ioutil.TempDir("", "astparser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "user.go"), querySources["models/user.go"], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"query", "fields where tag(db) == created_at", dir}, &stdout, &stderr); code != 0 {
+		t.Fatalf("run() = %d, stderr %s", code, stderr.String())
+	}
+	file := filepath.Join(dir, "user.go")
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "STRUCT") ||
+		!strings.Contains(lines[1], `CreatedAt  time.Time  db:"created_at"  `+file) {
+		t.Errorf("unexpected table\n%s", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := run([]string{"query", "-format", "json", "structs where tag(json)", dir}, &stdout, &stderr); code != 0 {
+		t.Fatalf("run() = %d, stderr %s", code, stderr.String())
+	}
+	var results []map[string]interface{}
+	if err := json.Unmarshal(stdout.Bytes(), &results); err != nil {
+		t.Fatalf("failed to decode output: %v\n%s", err, stdout.String())
+	}
+	want := []map[string]interface{}{{"id": filepath.ToSlash(dir) + ".User", "name": "User", "package": "models", "kind": "struct", "file": file}}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("\nhave %+v, \nwant %+v", results, want)
+	}
+
+	stderr.Reset()
+	if code := run([]string{"query", "structs where", dir}, &stdout, &stderr); code != 2 || !strings.Contains(stderr.String(), "invalid query") {
+		t.Errorf("run() = %d, stderr %q", code, stderr.String())
+	}
+}
@@ -1,16 +1,97 @@
 package astparser
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"go/build"
+	"go/token"
+	"io/fs"
+	"path"
+	"runtime"
+	"strings"
+)
 
 type Config struct {
-	InputDir      string
+	InputDir string
+	// InputDirs are more input dirs loaded together with InputDir.
+	InputDirs []string
+	// Recursive enables loading of the input dirs subdirectories,
+	// each directory makes its own package.
+	Recursive bool
+
+	// IncludeRegexp and ExcludeRegexp are matched against slash separated
+	// file paths relative to the input dir. Files matching IncludeRegexp are
+	// loaded unless they match ExcludeRegexp.
 	ExcludeRegexp string
 	IncludeRegexp string
+	// Patterns are doublestar globs like `**/event_*.go` matched against slash
+	// separated file paths relative to the input dir. If set, only matching files
+	// are loaded. Subdirectories are walked if a pattern contains a path separator.
+	Patterns []string
+	// ExcludePatterns are globs of files to skip, like `event_legacy*`.
+	ExcludePatterns []string
+
+	// FS is a file system to load files from instead of the OS one,
+	// e.g. embed.FS or zip.Reader. InputDir is a slash separated path inside it.
+	FS fs.FS
+	// Sources are in-memory file contents by slash separated file path,
+	// e.g. unsaved editor buffers. If set, FS is not used.
+	Sources map[string][]byte
+
+	// GOOS and GOARCH are used to match file names like `event_windows.go`
+	// and build constraints, default to the values of go/build.Default.
+	GOOS   string
+	GOARCH string
+	// BuildTags are additional build tags files are matched against.
+	BuildTags []string
+
+	// IncludeTests enables loading of `_test.go` files of the package itself.
+	IncludeTests bool
+	// IncludeExternalTests enables loading of `_test.go` files of the external `_test` package.
+	IncludeExternalTests bool
+
+	// Concurrency limits the number of files parsed in parallel,
+	// defaults to runtime.GOMAXPROCS(0).
+	Concurrency int
+	// Fset is a file set shared by all the parsed files, so their positions
+	// are comparable. A new one is created if nil. It must be nil for
+	// Loader and Watch, see Loader.
+	Fset *token.FileSet
+
+	// CacheDir is a directory parsed files are cached in by content hash,
+	// so unchanged files are not parsed again. Cached files are not added to Fset.
+	CacheDir string
+
+	// Observer is called when each file starts and finishes loading,
+	// e.g. to show progress. Calls are serialized.
+	Observer func(FileEvent)
+
+	// SkipGenerated skips files with the standard
+	// `// Code generated ... DO NOT EDIT.` header.
+	SkipGenerated bool
+
+	// Targets are code generators to run, set from the config file.
+	// They are not used for loading.
+	Targets []Target
 }
 
 func (c *Config) validate() error {
-	if c.IncludeRegexp != "" && c.ExcludeRegexp != "" {
-		return errors.New("both include and exclude regexps are set")
+	if c.FS != nil && c.Sources != nil {
+		return errors.New("both FS and Sources are set")
+	}
+
+	if c.FS != nil || c.Sources != nil {
+		for _, dir := range c.inputDirs() {
+			if !fs.ValidPath(path.Clean(dir)) {
+				return fmt.Errorf("input dir %q is not a valid io/fs path", dir)
+			}
+		}
+	}
+
+	for _, pattern := range append(append([]string(nil), c.Patterns...), c.ExcludePatterns...) {
+		if err := validateGlob(pattern); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -21,9 +102,59 @@ func (c *Config) prepare() error {
 		return err
 	}
 
-	if c.InputDir == "" {
+	if c.InputDir == "" && len(c.InputDirs) == 0 {
 		c.InputDir = "./"
+		if c.FS != nil || c.Sources != nil {
+			c.InputDir = "."
+		}
+	}
+
+	if c.Concurrency <= 0 {
+		c.Concurrency = runtime.GOMAXPROCS(0)
+	}
+
+	if c.Fset == nil {
+		c.Fset = token.NewFileSet()
 	}
 
 	return nil
 }
+
+// inputDirs returns InputDir and InputDirs.
+func (c *Config) inputDirs() []string {
+	var dirs []string
+	if c.InputDir != "" {
+		dirs = append(dirs, c.InputDir)
+	}
+	return append(dirs, c.InputDirs...)
+}
+
+// walkSubdirs reports whether input dirs subdirectories should be walked.
+func (c *Config) walkSubdirs() bool {
+	if c.Recursive {
+		return true
+	}
+	for _, pattern := range c.Patterns {
+		if strings.Contains(pattern, "/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Config) includeAnyTests() bool {
+	return c.IncludeTests || c.IncludeExternalTests
+}
+
+// buildContext returns go/build context files are matched with.
+func (c *Config) buildContext() *build.Context {
+	ctx := build.Default
+	if c.GOOS != "" {
+		ctx.GOOS = c.GOOS
+	}
+	if c.GOARCH != "" {
+		ctx.GOARCH = c.GOARCH
+	}
+	ctx.BuildTags = append(append([]string(nil), ctx.BuildTags...), c.BuildTags...)
+	return &ctx
+}
@@ -0,0 +1,243 @@
+package astparser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileNames are config file names FindConfigFile looks for, in order of preference.
+var ConfigFileNames = []string{".astparser.yaml", ".astparser.yml", ".astparser.json", ".astparser.toml"}
+
+// Target is a code generator target of the config file.
+type Target struct {
+	// Generator is a generator name, e.g. `jsonschema` or `typescript`.
+	Generator string `json:"generator" yaml:"generator" toml:"generator"`
+	// Output is a file or dir generated code is written to.
+	Output string `json:"output" yaml:"output" toml:"output"`
+	// Types are names of the root types to generate code for, all if empty.
+	Types []string `json:"types" yaml:"types" toml:"types"`
+	// Options are generator specific options.
+	Options map[string]string `json:"options" yaml:"options" toml:"options"`
+}
+
+// configFile is the config file schema:
+//
+//	version: 1
+//	inputs: [./models, ./dto]
+//	recursive: true
+//	include: ["**/event_*.go"]
+//	exclude: ["**/event_legacy*"]
+//	build:
+//	  goos: linux
+//	  tags: [integration]
+//	resolve:
+//	  tests: true
+//	  skip_generated: true
+//	generate:
+//	  - generator: typescript
+//	    output: web/models.d.ts
+type configFile struct {
+	Version       int         `json:"version" yaml:"version" toml:"version"`
+	Inputs        []string    `json:"inputs" yaml:"inputs" toml:"inputs"`
+	Recursive     bool        `json:"recursive" yaml:"recursive" toml:"recursive"`
+	Include       []string    `json:"include" yaml:"include" toml:"include"`
+	Exclude       []string    `json:"exclude" yaml:"exclude" toml:"exclude"`
+	IncludeRegexp string      `json:"include_regexp" yaml:"include_regexp" toml:"include_regexp"`
+	ExcludeRegexp string      `json:"exclude_regexp" yaml:"exclude_regexp" toml:"exclude_regexp"`
+	Build         buildFile   `json:"build" yaml:"build" toml:"build"`
+	Resolve       resolveFile `json:"resolve" yaml:"resolve" toml:"resolve"`
+	Concurrency   int         `json:"concurrency" yaml:"concurrency" toml:"concurrency"`
+	CacheDir      string      `json:"cache_dir" yaml:"cache_dir" toml:"cache_dir"`
+	Generate      []Target    `json:"generate" yaml:"generate" toml:"generate"`
+}
+
+type buildFile struct {
+	GOOS   string   `json:"goos" yaml:"goos" toml:"goos"`
+	GOARCH string   `json:"goarch" yaml:"goarch" toml:"goarch"`
+	Tags   []string `json:"tags" yaml:"tags" toml:"tags"`
+}
+
+type resolveFile struct {
+	Tests         bool `json:"tests" yaml:"tests" toml:"tests"`
+	ExternalTests bool `json:"external_tests" yaml:"external_tests" toml:"external_tests"`
+	SkipGenerated bool `json:"skip_generated" yaml:"skip_generated" toml:"skip_generated"`
+}
+
+// LoadConfigFile reads Config from YAML, JSON or TOML file chosen by the file extension.
+// Relative input and cache dirs are resolved against the config file dir,
+// which is the input dir if there are no inputs. Unknown keys are errors.
+func LoadConfigFile(path string) (Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Config{}, errors.Wrapf(err, "failed to read config file %s", path)
+	}
+
+	var file configFile
+	if err := decodeConfigFile(path, data, &file); err != nil {
+		return Config{}, errors.Wrapf(err, "failed to decode config file %s", path)
+	}
+	if err := file.validate(); err != nil {
+		return Config{}, errors.Wrapf(err, "invalid config file %s", path)
+	}
+
+	return file.config(filepath.Dir(path)), nil
+}
+
+// FindConfigFile looks for a config file in dir and its parents.
+// Returns empty path if there is none.
+func FindConfigFile(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for d := abs; ; d = filepath.Dir(d) {
+		for _, name := range ConfigFileNames {
+			p := filepath.Join(d, name)
+			info, err := os.Stat(p)
+			switch {
+			case err == nil && !info.IsDir():
+				return p, nil
+			case err != nil && !os.IsNotExist(err):
+				return "", err
+			}
+		}
+
+		if filepath.Dir(d) == d {
+			return "", nil
+		}
+	}
+}
+
+func decodeConfigFile(path string, data []byte, file *configFile) error {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		d := yaml.NewDecoder(bytes.NewReader(data))
+		d.KnownFields(true)
+		// empty file is a valid empty config.
+		if err := d.Decode(file); err != nil && len(bytes.TrimSpace(data)) > 0 {
+			return err
+		}
+		return nil
+	case ".json":
+		d := json.NewDecoder(bytes.NewReader(data))
+		d.DisallowUnknownFields()
+		return d.Decode(file)
+	case ".toml":
+		md, err := toml.Decode(string(data), file)
+		if err != nil {
+			return err
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			keys := make([]string, len(undecoded))
+			for i, key := range undecoded {
+				keys[i] = key.String()
+			}
+			sort.Strings(keys)
+			return fmt.Errorf("unknown keys %s", strings.Join(keys, ", "))
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported config file extension %q, want .yaml, .yml, .json or .toml", ext)
+	}
+}
+
+func (f *configFile) validate() error {
+	if f.Version != 0 && f.Version != 1 {
+		return fmt.Errorf("version: unsupported version %d, want 1", f.Version)
+	}
+
+	for i, input := range f.Inputs {
+		if strings.TrimSpace(input) == "" {
+			return fmt.Errorf("inputs[%d]: empty input dir", i)
+		}
+	}
+	for i, pattern := range f.Include {
+		if err := validateGlob(pattern); err != nil {
+			return fmt.Errorf("include[%d]: %v", i, err)
+		}
+	}
+	for i, pattern := range f.Exclude {
+		if err := validateGlob(pattern); err != nil {
+			return fmt.Errorf("exclude[%d]: %v", i, err)
+		}
+	}
+	if _, err := regexp.Compile(f.IncludeRegexp); err != nil {
+		return fmt.Errorf("include_regexp: %v", err)
+	}
+	if _, err := regexp.Compile(f.ExcludeRegexp); err != nil {
+		return fmt.Errorf("exclude_regexp: %v", err)
+	}
+
+	for i, tag := range f.Build.Tags {
+		if tag == "" || strings.ContainsAny(tag, " \t,!") {
+			return fmt.Errorf("build.tags[%d]: invalid build tag %q", i, tag)
+		}
+	}
+	if f.Concurrency < 0 {
+		return fmt.Errorf("concurrency: must not be negative, got %d", f.Concurrency)
+	}
+
+	for i, t := range f.Generate {
+		if t.Generator == "" {
+			return fmt.Errorf("generate[%d].generator: required", i)
+		}
+		if t.Output == "" {
+			return fmt.Errorf("generate[%d].output: required", i)
+		}
+	}
+
+	return nil
+}
+
+// config converts the file to Config resolving relative paths against dir.
+func (f *configFile) config(dir string) Config {
+	cfg := Config{
+		Recursive:            f.Recursive,
+		Patterns:             f.Include,
+		ExcludePatterns:      f.Exclude,
+		IncludeRegexp:        f.IncludeRegexp,
+		ExcludeRegexp:        f.ExcludeRegexp,
+		GOOS:                 f.Build.GOOS,
+		GOARCH:               f.Build.GOARCH,
+		BuildTags:            f.Build.Tags,
+		IncludeTests:         f.Resolve.Tests,
+		IncludeExternalTests: f.Resolve.ExternalTests,
+		SkipGenerated:        f.Resolve.SkipGenerated,
+		Concurrency:          f.Concurrency,
+		Targets:              f.Generate,
+	}
+
+	resolve := func(p string) string {
+		if filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, filepath.FromSlash(p))
+	}
+
+	for _, input := range f.Inputs {
+		cfg.InputDirs = append(cfg.InputDirs, resolve(input))
+	}
+	if len(cfg.InputDirs) == 0 {
+		cfg.InputDir = dir
+	}
+	if f.CacheDir != "" {
+		cfg.CacheDir = resolve(f.CacheDir)
+	}
+	for i, t := range cfg.Targets {
+		cfg.Targets[i].Output = resolve(t.Output)
+	}
+
+	return cfg
+}
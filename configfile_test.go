@@ -0,0 +1,176 @@
+package astparser
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "astparser-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	want := Config{
+		InputDirs:       []string{filepath.Join(dir, "models"), filepath.Join(dir, "dto")},
+		Recursive:       true,
+		Patterns:        []string{"**/event_*.go"},
+		ExcludePatterns: []string{"**/event_legacy*"},
+		GOOS:            "linux",
+		BuildTags:       []string{"integration"},
+		IncludeTests:    true,
+		SkipGenerated:   true,
+		CacheDir:        filepath.Join(dir, ".cache"),
+		Targets:         []Target{{Generator: "typescript", Output: filepath.Join(dir, "web/models.d.ts"), Types: []string{"Event"}}},
+	}
+
+	files := map[string]string{
+		".astparser.yaml": `
+version: 1
+inputs: [models, dto]
+recursive: true
+include: ["**/event_*.go"]
+exclude: ["**/event_legacy*"]
+build:
+  goos: linux
+  tags: [integration]
+resolve:
+  tests: true
+  skip_generated: true
+cache_dir: .cache
+generate:
+  - generator: typescript
+    output: web/models.d.ts
+    types: [Event]
+`,
+		".astparser.json": `{
+  "version": 1,
+  "inputs": ["models", "dto"],
+  "recursive": true,
+  "include": ["**/event_*.go"],
+  "exclude": ["**/event_legacy*"],
+  "build": {"goos": "linux", "tags": ["integration"]},
+  "resolve": {"tests": true, "skip_generated": true},
+  "cache_dir": ".cache",
+  "generate": [{"generator": "typescript", "output": "web/models.d.ts", "types": ["Event"]}]
+}`,
+		".astparser.toml": `
+version = 1
+inputs = ["models", "dto"]
+recursive = true
+include = ["**/event_*.go"]
+exclude = ["**/event_legacy*"]
+cache_dir = ".cache"
+
+[build]
+goos = "linux"
+tags = ["integration"]
+
+[resolve]
+tests = true
+skip_generated = true
+
+[[generate]]
+generator = "typescript"
+output = "web/models.d.ts"
+types = ["Event"]
+`,
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			p := filepath.Join(dir, name)
+			if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := LoadConfigFile(p)
+			if err != nil {
+				t.Fatalf("LoadConfigFile() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("\nhave %+v, \nwant %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFile_errors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "astparser-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr string
+	}{
+		{name: "unknown yaml key", file: "a.yaml", content: "inputz: [models]\n", wantErr: "inputz"},
+		{name: "unknown json key", file: "a.json", content: `{"build": {"os": "linux"}}`, wantErr: "os"},
+		{name: "unknown toml key", file: "a.toml", content: "[build]\nos = \"linux\"\n", wantErr: "build.os"},
+		{name: "wrong type", file: "a.yaml", content: "recursive: sometimes\n", wantErr: "sometimes"},
+		{name: "version", file: "a.yaml", content: "version: 2\n", wantErr: "version: unsupported version 2"},
+		{name: "glob", file: "a.yaml", content: "exclude: [ok, \"event_[.go\"]\n", wantErr: "exclude[1]"},
+		{name: "regexp", file: "a.yaml", content: "include_regexp: \"(\"\n", wantErr: "include_regexp"},
+		{name: "build tag", file: "a.yaml", content: "build:\n  tags: [\"a b\"]\n", wantErr: "build.tags[0]"},
+		{name: "generator output", file: "a.yaml", content: "generate:\n  - generator: proto\n", wantErr: "generate[0].output: required"},
+		{name: "extension", file: "a.ini", content: "", wantErr: "unsupported config file extension"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := filepath.Join(dir, tt.file)
+			if err := ioutil.WriteFile(p, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := LoadConfigFile(p)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) || !strings.Contains(err.Error(), p) {
+				t.Errorf("LoadConfigFile() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFindConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "astparser-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	nested := filepath.Join(dir, "a", "b")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := FindConfigFile(nested)
+	if err != nil || got != "" {
+		t.Fatalf("FindConfigFile() = %q, %v, want no file", got, err)
+	}
+
+	want := filepath.Join(dir, "a", ".astparser.yaml")
+	if err := ioutil.WriteFile(want, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err = FindConfigFile(nested)
+	if err != nil || got != want {
+		t.Errorf("FindConfigFile() = %q, %v, want %q", got, err, want)
+	}
+
+	cfg, err := LoadConfigFile(got)
+	if err != nil {
+		t.Fatalf("LoadConfigFile() error = %v", err)
+	}
+	if cfg.InputDir != filepath.Dir(want) {
+		t.Errorf("InputDir = %q, want config file dir", cfg.InputDir)
+	}
+}
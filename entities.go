@@ -1,27 +1,125 @@
 package astparser
 
-import "go/ast"
+import (
+	"go/ast"
+	"go/token"
+)
 
+// ParsedFile contains declarations parsed from a single go file.
 type ParsedFile struct {
-	Structs   []StructDef
-	Constants []ConstantDef
-	Package   string
+	// Path is the file path as it was passed to the parser.
+	Path      string        `json:"path"`
+	Structs   []StructDef   `json:"structs"`
+	Types     []TypeDef     `json:"types"`
+	Constants []ConstantDef `json:"constants"`
+	Funcs     []FuncDef     `json:"funcs"`
+	Imports   []ImportDef   `json:"imports"`
+	Package   string        `json:"package"`
+	// PackageDoc contains the package doc comment if the file has one.
+	PackageDoc []string `json:"package_doc"`
+	// IsTest is true for `_test.go` files loaded with Config.IncludeTests
+	// or Config.IncludeExternalTests.
+	IsTest bool `json:"is_test"`
+	// IsGenerated is true for files with the standard
+	// `// Code generated ... DO NOT EDIT.` header.
+	IsGenerated bool `json:"is_generated"`
+	// BuildConstraint is the file build constraint expression like `linux && !cgo`,
+	// empty if the file has no constraints.
+	BuildConstraint string `json:"build_constraint"`
 }
 
-// Type represent parsed type.
-type Type interface{}
+// ImportDef describes a single import of a file.
+type ImportDef struct {
+	// Name is an explicit import name like `json`, `_` or `.`, empty if not set.
+	Name     string         `json:"name"`
+	Path     string         `json:"path"`
+	Comments []string       `json:"comments"`
+	Pos      token.Position `json:"pos"`
+}
+
+// Qualifier returns the name the import is referenced by in the file.
+// Without explicit name it is guessed from the import path like
+// goimports does, so `gopkg.in/yaml.v3` gives `yaml` and
+// `github.com/go-chi/chi/v5` gives `chi`.
+func (i ImportDef) Qualifier() string {
+	if i.Name != "" {
+		return i.Name
+	}
+	return importPathToName(i.Path)
+}
+
+// ImportPath returns the path of the import referenced by qualifier,
+// e.g. `time` for `time.Time`. Blank and dot imports are never matched.
+func (f ParsedFile) ImportPath(qualifier string) (string, bool) {
+	for _, i := range f.Imports {
+		if i.Name == "_" || i.Name == "." {
+			continue
+		}
+		if i.Qualifier() == qualifier {
+			return i.Path, true
+		}
+	}
+	return "", false
+}
+
+// Type represent parsed type. It is implemented by TypeSimple, TypeArray,
+// TypeMap, TypeCustom, TypePointer and TypeInterfaceValue only.
+type Type interface {
+	// Kind returns the type kind, so types could be told apart without a type switch.
+	Kind() Kind
+	// String renders the type as Go source, like `map[string][]*Dep`.
+	String() string
+	// Equal compares types structurally, ast expressions are ignored.
+	Equal(Type) bool
+
+	// isType seals the interface.
+	isType()
+}
 
 // ConstantDef describes defined constants
 type ConstantDef struct {
-	Name  string
-	Value string
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// Type is the declared type like `MyEnum`, empty for untyped constants and variables.
+	Type string `json:"type"`
+	File string `json:"file"`
 }
 
 // StructDef describes parsed go struct.
 type StructDef struct {
-	Name     string
-	Fields   []FieldDef
-	Comments []string
+	Name     string     `json:"name"`
+	Fields   []FieldDef `json:"fields"`
+	Comments []string   `json:"comments"`
+	File     string     `json:"file"`
+}
+
+// TypeDef describes a named non-struct type like `type MyEnum string`
+// or an alias like `type A = B`.
+type TypeDef struct {
+	Name string `json:"name"`
+	// Type is the underlying type.
+	Type Type `json:"type"`
+	// Alias is true for alias declarations `type A = B`.
+	Alias bool `json:"alias"`
+	// Methods are methods of interface types.
+	Methods []FuncDef `json:"methods"`
+	// Embedded are embedded interfaces of interface types, like `io.Reader`.
+	Embedded []string `json:"embedded"`
+	Comments []string `json:"comments"`
+	File     string   `json:"file"`
+}
+
+// FuncDef describes a function or a method declaration.
+type FuncDef struct {
+	Name string `json:"name"`
+	// Receiver contains receiver type name for methods, like `*Struct`.
+	// Empty for plain functions.
+	Receiver string `json:"receiver"`
+	// Signature is the function type without parameter names,
+	// like `func(context.Context, string) (*User, error)`.
+	Signature string   `json:"signature"`
+	Comments  []string `json:"comments"`
+	File      string   `json:"file"`
 }
 
 // Tag contains parsed field tags.
@@ -33,15 +131,16 @@ type Tag struct {
 }
 
 // FieldDef described parsed go struct field.
+// Fields of types the model can't represent, like func or chan fields, are skipped.
 type FieldDef struct {
-	CompositionField bool
+	CompositionField bool `json:"composition_field"`
 	// Could be empty for CompositionField
-	FieldName string
-	FieldType Type
-	JsonName  string
-	Nullable  bool
-	Comments  []string
-	AllTags   map[string]string
+	FieldName string            `json:"field_name"`
+	FieldType Type              `json:"field_type"`
+	JsonName  string            `json:"json_name"`
+	Nullable  bool              `json:"nullable"`
+	Comments  []string          `json:"comments"`
+	AllTags   map[string]string `json:"all_tags"`
 }
 
 // TypeSimple indicates that type is a primitive golang type like int or string.
@@ -66,11 +165,14 @@ type TypeMap struct {
 type TypeCustom struct {
 	// type alias. like `type myCost string`
 	// is true when we cant resolve alias type.
-	Alias     bool
+	Alias bool
 	// contains the alias type
 	AliasType Type
 	Name      string
-	Expr      ast.Expr
+	// Qualifier is a package name for types from other packages,
+	// e.g. `time` for `time.Time`.
+	Qualifier string
+	Expr      ast.Expr `json:"-"`
 }
 
 // TypePointer indicates that type is a point with underlying any golang type
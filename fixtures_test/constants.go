@@ -12,3 +12,23 @@ const (
 	MyEnumValue1 MyEnum = "enum-1"
 	MyEnumValue2 MyEnum = "enum-2"
 )
+
+// DefaultMyEnum is a variable, it is not an enum value.
+var DefaultMyEnum MyEnum = "default"
+
+type Status int
+
+const (
+	StatusUnknown Status = iota
+	StatusActive
+	_
+	StatusBlocked
+	StatusDeleted Status = StatusBlocked + 10
+)
+
+const (
+	KB = 1 << (10 * (iota + 1))
+	MB
+)
+
+const Half = float64(1) / 2
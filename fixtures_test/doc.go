@@ -0,0 +1,23 @@
+// Package fixtures_test contains types for astparser tests.
+package fixtures_test
+
+import (
+	"strings"
+	"time"
+)
+
+type Event struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Title returns event name in title case.
+func (e *Event) Title() string {
+	return strings.Title(e.Name)
+}
+
+// NewEvent creates new event.
+func NewEvent(name string) Event {
+	const local = "local"
+	return Event{Name: name + local, CreatedAt: time.Now()}
+}
@@ -0,0 +1,5 @@
+package fixtures_test
+
+type EventFixture struct {
+	Event Event `json:"event"`
+}
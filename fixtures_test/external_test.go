@@ -0,0 +1,5 @@
+package fixtures_test_test
+
+type ExternalFixture struct {
+	Name string `json:"name"`
+}
@@ -0,0 +1,11 @@
+//go:build ignore
+// +build ignore
+
+package main
+
+// Event clashes with fixtures_test.Event and must never be loaded.
+type Event struct {
+	ID int `json:"id"`
+}
+
+func main() {}
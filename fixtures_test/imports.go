@@ -0,0 +1,16 @@
+package fixtures_test
+
+import (
+	// embed is imported for go:embed directives.
+	_ "embed"
+	js "encoding/json"
+	. "math"
+	"net/http" // http is used by Request
+)
+
+type Request struct {
+	Body    js.RawMessage `json:"body"`
+	Headers http.Header   `json:"headers"`
+}
+
+var precision = Pi
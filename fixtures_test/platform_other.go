@@ -0,0 +1,9 @@
+//go:build !windows && (amd64 || arm64)
+// +build !windows
+// +build amd64 arm64
+
+package fixtures_test
+
+type Platform struct {
+	Mount string `json:"mount"`
+}
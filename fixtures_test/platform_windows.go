@@ -0,0 +1,5 @@
+package fixtures_test
+
+type Platform struct {
+	Drive string `json:"drive"`
+}
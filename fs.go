@@ -0,0 +1,318 @@
+package astparser
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// source is an input dir files are loaded from.
+type source struct {
+	fsys fs.FS
+	// root is the input dir path inside fsys.
+	root string
+	// osDir is set when files are read from the OS file system
+	// to keep ParsedFile.Path relative to the working directory.
+	osDir string
+	// recursive is true if subdirectories are loaded too.
+	recursive bool
+}
+
+// newSources returns sources of all the config input dirs.
+func newSources(cfg Config) []source {
+	var fsys fs.FS
+	switch {
+	case cfg.Sources != nil:
+		fsys = newSourcesFS(cfg.Sources)
+	case cfg.FS != nil:
+		fsys = cfg.FS
+	}
+
+	recursive := cfg.walkSubdirs()
+	var sources []source
+	for _, dir := range cfg.inputDirs() {
+		if fsys != nil {
+			sources = append(sources, source{fsys: fsys, root: path.Clean(dir), recursive: recursive})
+			continue
+		}
+		sources = append(sources, source{fsys: os.DirFS(dir), root: ".", osDir: dir, recursive: recursive})
+	}
+	return sources
+}
+
+// path returns fsys path of the input dir file.
+func (s source) path(name string) string {
+	return path.Join(s.root, name)
+}
+
+// displayPath returns the file path reported in ParsedFile.Path.
+func (s source) displayPath(name string) string {
+	if s.osDir != "" {
+		return filepath.Join(s.osDir, filepath.FromSlash(name))
+	}
+	return s.path(name)
+}
+
+func (s source) readFile(name string) ([]byte, error) {
+	return fs.ReadFile(s.fsys, s.path(name))
+}
+
+// relName returns the input dir file name of the path
+// or false if the path is outside of the input dir.
+func (s source) relName(p string) (string, bool) {
+	var name string
+	if s.osDir != "" {
+		dir, err := filepath.Abs(s.osDir)
+		if err != nil {
+			return "", false
+		}
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return "", false
+		}
+		rel, err := filepath.Rel(dir, abs)
+		if err != nil {
+			return "", false
+		}
+		name = filepath.ToSlash(rel)
+	} else {
+		name = path.Clean(p)
+		if s.root != "." {
+			if !strings.HasPrefix(name, s.root+"/") {
+				return "", false
+			}
+			name = name[len(s.root)+1:]
+		}
+	}
+
+	if name == "." || name == ".." || strings.HasPrefix(name, "../") {
+		return "", false
+	}
+	if !s.recursive && strings.Contains(name, "/") {
+		return "", false
+	}
+	return name, true
+}
+
+// walk calls fn for each input dir file name, subdirectories are walked
+// if the source is recursive. Dirs go tool ignores are skipped.
+func (s source) walk(fn func(name string, d fs.DirEntry) error) error {
+	return fs.WalkDir(s.fsys, s.root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == s.root {
+			return nil
+		}
+		if d.IsDir() {
+			if !s.recursive || ignoredDir(d.Name()) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		name := p
+		if s.root != "." {
+			name = p[len(s.root)+1:]
+		}
+		return fn(name, d)
+	})
+}
+
+func ignoredDir(name string) bool {
+	return strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") ||
+		name == "testdata" || name == "vendor"
+}
+
+// importPathResolver resolves import paths of package dirs from the nearest go.mod.
+type importPathResolver struct {
+	// fsys is nil for OS dirs.
+	fsys  fs.FS
+	cache map[string]string
+}
+
+func newImportPathResolver(sources []source) *importPathResolver {
+	r := &importPathResolver{cache: map[string]string{}}
+	// all the sources share the same file system.
+	if len(sources) > 0 && sources[0].osDir == "" {
+		r.fsys = sources[0].fsys
+	}
+	return r
+}
+
+// resolve returns the import path of dir in ParsedFile.Path form.
+func (r *importPathResolver) resolve(dir string) (string, error) {
+	if importPath, ok := r.cache[dir]; ok {
+		return importPath, nil
+	}
+
+	var importPath string
+	var err error
+	if r.fsys == nil {
+		importPath, err = resolveImportPath(dir)
+	} else {
+		importPath, err = resolveFSImportPath(r.fsys, path.Clean(filepath.ToSlash(dir)))
+	}
+	if err != nil {
+		return "", err
+	}
+
+	r.cache[dir] = importPath
+	return importPath, nil
+}
+
+// resolveFSImportPath is resolveImportPath for io/fs dirs.
+func resolveFSImportPath(fsys fs.FS, dir string) (string, error) {
+	for d := dir; ; d = path.Dir(d) {
+		data, err := fs.ReadFile(fsys, path.Join(d, "go.mod"))
+		switch {
+		case err == nil:
+			module := modulePath(data)
+			if module == "" {
+				return "", nil
+			}
+			rel := dir
+			if d != "." {
+				rel = strings.TrimPrefix(strings.TrimPrefix(dir, d), "/")
+			}
+			return path.Join(module, rel), nil
+		case !os.IsNotExist(err):
+			return "", err
+		}
+
+		if d == "." {
+			return "", nil
+		}
+	}
+}
+
+// sourcesFS is an in-memory file system made of Config.Sources.
+type sourcesFS map[string][]byte
+
+func newSourcesFS(sources map[string][]byte) sourcesFS {
+	fsys := make(sourcesFS, len(sources))
+	for name, data := range sources {
+		fsys[path.Clean(filepath.ToSlash(name))] = data
+	}
+	return fsys
+}
+
+// Open implements fs.FS.
+func (s sourcesFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if data, ok := s[name]; ok {
+		info := sourceInfo{name: path.Base(name), size: int64(len(data))}
+		return &sourceFile{info: info, Reader: bytes.NewReader(data)}, nil
+	}
+
+	entries, err := s.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+	return &sourceDir{info: sourceInfo{name: path.Base(name), dir: true}, entries: entries}, nil
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (s sourcesFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	prefix := name + "/"
+	if name == "." {
+		prefix = ""
+	}
+
+	seen := map[string]bool{}
+	var entries []fs.DirEntry
+	for file, data := range s {
+		if !strings.HasPrefix(file, prefix) {
+			continue
+		}
+		rest := file[len(prefix):]
+		entry := sourceInfo{name: rest, size: int64(len(data))}
+		if i := strings.Index(rest, "/"); i >= 0 {
+			entry = sourceInfo{name: rest[:i], dir: true}
+		}
+		if seen[entry.name] {
+			continue
+		}
+		seen[entry.name] = true
+		entries = append(entries, entry)
+	}
+
+	if entries == nil && name != "." {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// sourceInfo implements both fs.FileInfo and fs.DirEntry.
+type sourceInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (i sourceInfo) Name() string               { return i.name }
+func (i sourceInfo) Size() int64                { return i.size }
+func (i sourceInfo) ModTime() time.Time         { return time.Time{} }
+func (i sourceInfo) IsDir() bool                { return i.dir }
+func (i sourceInfo) Sys() interface{}           { return nil }
+func (i sourceInfo) Info() (fs.FileInfo, error) { return i, nil }
+func (i sourceInfo) Type() fs.FileMode          { return i.Mode().Type() }
+
+func (i sourceInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+type sourceFile struct {
+	*bytes.Reader
+	info sourceInfo
+}
+
+func (f *sourceFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *sourceFile) Close() error               { return nil }
+
+type sourceDir struct {
+	info    sourceInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *sourceDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *sourceDir) Close() error               { return nil }
+
+func (d *sourceDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: fs.ErrInvalid}
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (d *sourceDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.offset += n
+	return rest[:n], nil
+}
@@ -0,0 +1,207 @@
+package astparser
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad_sources(t *testing.T) {
+	cfg := Config{
+		InputDir: "models",
+		Sources: map[string][]byte{
+			"go.mod":               []byte("module example.com/app\n"),
+			"models/event.go":      []byte("package models\n\ntype Event struct {\n\tName string `json:\"name\"`\n}\n"),
+			"models/event_test.go": []byte("package models\n\ntype EventFixture struct{}\n"),
+			"models/README.md":     []byte("# models\n"),
+			"other/other.go":       []byte("package other\n\ntype Other struct{}\n"),
+		},
+	}
+
+	packages, err := LoadPackages(cfg)
+	if err != nil {
+		t.Fatalf("LoadPackages() error = %v", err)
+	}
+	if len(packages) != 1 {
+		t.Fatalf("expected 1 package, got %d", len(packages))
+	}
+
+	p := packages[0]
+	if p.ImportPath != "example.com/app/models" {
+		t.Errorf("ImportPath = %q", p.ImportPath)
+	}
+	want := []StructDef{{
+		Name: "Event",
+		File: "models/event.go",
+		Fields: []FieldDef{{
+			FieldName: "Name",
+			FieldType: TypeSimple{Name: "string"},
+			JsonName:  "name",
+			AllTags:   map[string]string{"json": "name"},
+		}},
+	}}
+	if !reflect.DeepEqual(p.Structs, want) {
+		t.Errorf("\nhave %+v, \nwant %+v", p.Structs, want)
+	}
+}
+
+func TestLoadPackages_inputs(t *testing.T) {
+	sources := map[string][]byte{
+		"go.mod":                      []byte("module example.com/app\n"),
+		"models/event_new.go":         []byte("package models\n\ntype EventNew struct{}\n"),
+		"models/event_legacy_v1.go":   []byte("package models\n\ntype EventLegacy struct{}\n"),
+		"models/user.go":              []byte("package models\n\ntype User struct{}\n"),
+		"models/audit/event_audit.go": []byte("package audit\n\ntype EventAudit struct{}\n"),
+		"models/testdata/event_x.go":  []byte("package testdata\n\ntype EventX struct{}\n"),
+		"dto/event_dto.go":            []byte("package dto\n\ntype EventDTO struct{}\n"),
+	}
+
+	tests := []struct {
+		name string
+		cfg  Config
+		want map[string][]string
+	}{
+		{
+			name: "single dir",
+			cfg:  Config{InputDir: "models"},
+			want: map[string][]string{
+				"example.com/app/models": {"EventLegacy", "EventNew", "User"},
+			},
+		},
+		{
+			name: "recursive",
+			cfg:  Config{InputDir: "models", Recursive: true},
+			want: map[string][]string{
+				"example.com/app/models":       {"EventLegacy", "EventNew", "User"},
+				"example.com/app/models/audit": {"EventAudit"},
+			},
+		},
+		{
+			name: "include and exclude regexps",
+			cfg:  Config{InputDir: "models", IncludeRegexp: "^event_", ExcludeRegexp: "^event_legacy"},
+			want: map[string][]string{
+				"example.com/app/models": {"EventNew"},
+			},
+		},
+		{
+			name: "regexps match relative paths",
+			cfg:  Config{InputDir: "models", Recursive: true, IncludeRegexp: "^audit/"},
+			want: map[string][]string{
+				"example.com/app/models/audit": {"EventAudit"},
+			},
+		},
+		{
+			name: "patterns",
+			cfg:  Config{InputDir: "models", Patterns: []string{"**/event_*.go"}, ExcludePatterns: []string{"event_legacy*"}},
+			want: map[string][]string{
+				"example.com/app/models":       {"EventNew"},
+				"example.com/app/models/audit": {"EventAudit"},
+			},
+		},
+		{
+			name: "multiple roots",
+			cfg:  Config{InputDirs: []string{"models", "dto"}, Patterns: []string{"event_*.go"}},
+			want: map[string][]string{
+				"example.com/app/dto":    {"EventDTO"},
+				"example.com/app/models": {"EventLegacy", "EventNew"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Sources = sources
+			packages, err := LoadPackages(tt.cfg)
+			if err != nil {
+				t.Fatalf("LoadPackages() error = %v", err)
+			}
+
+			got := map[string][]string{}
+			for _, p := range packages {
+				for _, s := range p.Structs {
+					got[p.ImportPath] = append(got[p.ImportPath], s.Name)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\nhave %+v, \nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_multipleRoots(t *testing.T) {
+	sources := map[string][]byte{
+		"models/event.go": []byte("package models\n"),
+		"dto/event.go":    []byte("package dto\n"),
+	}
+	if _, err := Load(Config{InputDirs: []string{"models", "dto"}, Sources: sources}); err == nil {
+		t.Error("expected several input dirs error")
+	}
+
+	packages, err := LoadPackages(Config{InputDirs: []string{"models", "dto"}, Sources: sources})
+	if err != nil {
+		t.Fatalf("LoadPackages() error = %v", err)
+	}
+	if len(packages) != 2 || packages[0].Files[0].Path != "dto/event.go" || packages[1].Files[0].Path != "models/event.go" {
+		t.Errorf("unexpected packages %+v", packages)
+	}
+}
+
+func TestLoad_invalidPattern(t *testing.T) {
+	if _, err := Load(Config{InputDir: "fixtures_test", Patterns: []string{"event_[.go"}}); err == nil {
+		t.Error("expected error for malformed pattern")
+	}
+
+	// the error doesn't dump the config with its sources.
+	sources := map[string][]byte{"a.go": []byte("package a")}
+	_, err := Load(Config{Sources: sources, Patterns: []string{"["}})
+	if err == nil || !strings.HasPrefix(err.Error(), "invalid config: ") || strings.Contains(err.Error(), "Sources") {
+		t.Errorf("Load() error = %v", err)
+	}
+}
+
+func TestLoad_fs(t *testing.T) {
+	fsys := fstest.MapFS{
+		"event.go":         {Data: []byte("package models\n\ntype Event struct{}\n")},
+		"event_windows.go": {Data: []byte("package models\n\ntype Windows struct{}\n")},
+	}
+
+	files, err := Load(Config{FS: fsys, GOOS: "linux"})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(files) != 1 || files["event.go"].Path != "event.go" {
+		t.Errorf("unexpected files %+v", files)
+	}
+}
+
+func TestLoad_osFSEquivalence(t *testing.T) {
+	fromDisk, err := Load(Config{InputDir: "fixtures_test"})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	fromFS, err := Load(Config{FS: os.DirFS("."), InputDir: "fixtures_test"})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(fromDisk) != len(fromFS) {
+		t.Fatalf("have %d files from FS, want %d", len(fromFS), len(fromDisk))
+	}
+	for name, f := range fromDisk {
+		if f.Path != fromFS[name].Path || len(f.Structs) != len(fromFS[name].Structs) {
+			t.Errorf("%s differs: %s vs %s", name, f.Path, fromFS[name].Path)
+		}
+	}
+}
+
+func Test_sourcesFS(t *testing.T) {
+	fsys := newSourcesFS(map[string][]byte{
+		"a.go":     []byte("package a"),
+		"dir/b.go": []byte("package b"),
+		"dir/c/d":  []byte("d"),
+	})
+	if err := fstest.TestFS(fsys, "a.go", "dir/b.go", "dir/c/d"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,114 @@
+// Package gentest contains the module shared by the generators tests.
+package gentest
+
+import (
+	"path"
+	"sort"
+	"testing"
+
+	"github.com/mkorolyov/astparser"
+)
+
+// EventSource is the `models/event.go` file of the test module.
+const EventSource = `package models
+
+import (
+	"time"
+
+	"example.com/app/dto"
+	"github.com/google/uuid"
+)
+
+// Event is a user action.
+// +table=events
+type Event struct {
+	Base
+	// Kind is the event kind.
+	Kind     Kind               ` + "`json:\"kind\"`" + `
+	At       time.Time          ` + "`json:\"at\"`" + `
+	User     *dto.User          ` + "`json:\"user\"`" + `
+	Email    string             ` + "`json:\"email\" format:\"email\" example:\"a@b.c\"`" + `
+	Payload  []byte             ` + "`json:\"payload,omitempty\"`" + `
+	Labels   map[string]string  ` + "`json:\"labels\" nullable:\"true\" protobuf:\"bytes,10,rep,name=labels\"`" + `
+	Scores   map[string]float64 ` + "`json:\"scores\"`" + `
+	Levels   []*Level           ` + "`json:\"content-levels\"`" + `
+	Sizes    []int              ` + "`json:\"sizes\" example:\"[1,2]\"`" + `
+	Count    *int32             ` + "`json:\"count,omitempty\"`" + `
+	Rate     *float32           ` + "`json:\"rate,omitempty\"`" + `
+	Parent   *Event             ` + "`json:\"parent\"`" + `
+	Trace    uuid.UUID          ` + "`json:\"trace\"`" + `
+	UserID   UserID             ` + "`json:\"userId\"`" + `
+	Priority Priority           ` + "`json:\"priority\"`" + `
+	Extra    interface{}
+	Secret   string ` + "`json:\"-\"`" + `
+	secret   string
+}
+
+type Base struct {
+	// ID is the event id.
+	ID int64 ` + "`json:\"id\" example:\"42\" protobuf:\"1\"`" + `
+}
+
+type UserID string
+
+// Kind is the event kind.
+//
+// Kinds are stored as strings.
+type Kind string
+
+const (
+	KindCreated Kind = "created"
+	KindDeleted Kind = "deleted"
+)
+
+type Level int
+
+const (
+	LevelLow  Level = 1
+	LevelHigh Level = 2
+)
+
+type Priority uint8
+
+const (
+	PriorityLow  Priority = 1
+	PriorityHigh Priority = 2
+)
+`
+
+// UserSource is the `dto/user.go` file of the test module.
+const UserSource = `package dto
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+`
+
+// Sources returns a new copy of the test module sources, tests could change them.
+func Sources() map[string][]byte {
+	return map[string][]byte{
+		"go.mod":          []byte("module example.com/app\n"),
+		"models/event.go": []byte(EventSource),
+		"dto/user.go":     []byte(UserSource),
+	}
+}
+
+// Model loads the model of the sources packages.
+func Model(t testing.TB, sources map[string][]byte) *astparser.Model {
+	t.Helper()
+	seen := map[string]bool{}
+	var dirs []string
+	for name := range sources {
+		if dir := path.Dir(name); dir != "." && !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+
+	packages, err := astparser.LoadPackages(astparser.Config{InputDirs: dirs, Sources: sources})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return astparser.NewModel(packages)
+}
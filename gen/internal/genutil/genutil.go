@@ -0,0 +1,68 @@
+// Package genutil contains helpers shared by the generators.
+package genutil
+
+import (
+	"strings"
+
+	"github.com/mkorolyov/astparser"
+)
+
+// IDs of the common types generators map to their own well-known types.
+const (
+	Time       = "time.Time"
+	Duration   = "time.Duration"
+	RawMessage = "encoding/json.RawMessage"
+	Number     = "encoding/json.Number"
+	URL        = "net/url.URL"
+	IP         = "net.IP"
+	BigInt     = "math/big.Int"
+	UUID       = "github.com/google/uuid.UUID"
+)
+
+// Names names the model types by their names, or by qualify if the name is used
+// in several packages. Names are keyed by type ID.
+func Names(model *astparser.Model, qualify func(n *astparser.TypeNode) string) map[string]string {
+	nodes := model.Graph().Nodes()
+	count := map[string]int{}
+	for _, n := range nodes {
+		count[n.Name]++
+	}
+	names := make(map[string]string, len(nodes))
+	for _, n := range nodes {
+		if count[n.Name] > 1 {
+			names[n.ID] = qualify(n)
+		} else {
+			names[n.ID] = n.Name
+		}
+	}
+	return names
+}
+
+// PackageTypeName qualifies the type by package and type names like `ModelsUser`.
+func PackageTypeName(n *astparser.TypeNode) string {
+	return strings.ToUpper(n.Package.Name[:1]) + n.Package.Name[1:] + n.Name
+}
+
+// IsBytes reports whether the type is a byte slice, encoding/json encodes them as base64 strings.
+func IsBytes(t astparser.Type) bool {
+	a, ok := t.(astparser.TypeArray)
+	if !ok {
+		return false
+	}
+	inner, ok := a.InnerType.(astparser.TypeSimple)
+	return ok && (inner.Name == "byte" || inner.Name == "uint8")
+}
+
+// DocLines returns doc comment lines without annotations like `+enum` and trailing empty lines.
+func DocLines(comments []string) []string {
+	var lines []string
+	for _, c := range comments {
+		if !strings.HasPrefix(c, "+") {
+			lines = append(lines, c)
+		}
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,252 @@
+// Package jsonschema generates JSON Schema Draft 2020-12 documents
+// of the model structs as encoding/json encodes them.
+//
+// Structs are objects with properties named by JSONName, fields without
+// omitempty or nullable tags are required. Pointers and `nullable:"true"`
+// fields also match null. Referenced named types are put into $defs, typed
+// constants of a named type are its enum, doc comments are descriptions.
+// Field `format:"email"` and `example:"a@b.c"` tags set format and examples.
+package jsonschema
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/mkorolyov/astparser"
+	"github.com/mkorolyov/astparser/gen/internal/genutil"
+)
+
+// Options configure the generator.
+type Options struct {
+	// RefPrefix is the prefix of definition references, `#/$defs/` by default.
+	RefPrefix string
+	// Types override schemas of the types by ID like `github.com/google/uuid.UUID`.
+	Types map[string]*Schema
+	// Formats are formats of predeclared types by name, like `int64` for OpenAPI.
+	Formats map[string]string
+}
+
+// Generator generates schemas of the model types.
+type Generator struct {
+	model *astparser.Model
+	opts  Options
+	// names are definition names by type ID.
+	names map[string]string
+}
+
+// wellKnown are schemas of the common types encoded as JSON strings or numbers.
+var wellKnown = map[string]Schema{
+	genutil.Time:       {Type: Types{"string"}, Format: "date-time"},
+	genutil.Duration:   {Type: Types{"integer"}},
+	genutil.RawMessage: {},
+	genutil.Number:     {Type: Types{"number"}},
+	genutil.URL:        {Type: Types{"string"}, Format: "uri"},
+	genutil.IP:         {Type: Types{"string"}},
+	genutil.BigInt:     {Type: Types{"integer"}},
+	genutil.UUID:       {Type: Types{"string"}, Format: "uuid"},
+}
+
+// New creates a generator of the model types schemas.
+func New(model *astparser.Model, opts Options) *Generator {
+	if opts.RefPrefix == "" {
+		opts.RefPrefix = "#/$defs/"
+	}
+	return &Generator{model: model, opts: opts, names: genutil.Names(model, definitionName)}
+}
+
+// definitionName qualifies definition names used in several packages like `models.User`.
+func definitionName(n *astparser.TypeNode) string {
+	return n.Package.Name + "." + n.Name
+}
+
+// Name returns the definition name of the type by ID.
+func (g *Generator) Name(id string) string {
+	return g.names[id]
+}
+
+// Schema returns the schema document of the type found by ID or unique name.
+// The document references the type definition, definitions of all the types
+// it references are in $defs.
+func (g *Generator) Schema(root string) (*Schema, error) {
+	id, err := g.model.Graph().Find(root)
+	if err != nil {
+		return nil, err
+	}
+	defs, err := g.Definitions(id)
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{Schema: Draft, Ref: g.opts.RefPrefix + g.names[id], Defs: defs}, nil
+}
+
+// Definitions returns schemas by definition name of the types found by ID
+// or unique name, and of all the types they reference.
+func (g *Generator) Definitions(roots ...string) (map[string]*Schema, error) {
+	var queue []*astparser.TypeNode
+	for _, root := range roots {
+		id, err := g.model.Graph().Find(root)
+		if err != nil {
+			return nil, err
+		}
+		n, _ := g.model.Graph().Node(id)
+		queue = append(queue, n)
+	}
+
+	defs := map[string]*Schema{}
+	seen := map[string]bool{}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		if seen[n.ID] {
+			continue
+		}
+		seen[n.ID] = true
+
+		var refs []*astparser.TypeNode
+		defs[g.names[n.ID]] = g.definition(n, &refs)
+		queue = append(queue, refs...)
+	}
+	return defs, nil
+}
+
+// definition returns the schema of the struct or the named type,
+// refs collects the referenced loaded types.
+func (g *Generator) definition(n *astparser.TypeNode, refs *[]*astparser.TypeNode) *Schema {
+	if n.Struct == nil {
+		s := g.typeSchema(n, n.Type.Type, refs)
+		c := *s
+		c.Description = description(n.Comments())
+		for _, constant := range g.model.Constants(n) {
+			c.Enum = append(c.Enum, jsonValue(c.Type, constant.Value))
+		}
+		return &c
+	}
+
+	s := &Schema{Type: Types{"object"}, Description: description(n.Comments()), Properties: Properties{}}
+	for _, f := range g.model.JSONFields(n) {
+		s.Properties = append(s.Properties, Property{Name: f.Name, Schema: g.FieldSchema(f, refs)})
+		if !f.Field.Nullable {
+			s.Required = append(s.Required, f.Name)
+		}
+	}
+	return s
+}
+
+// FieldSchema returns the struct field property schema, refs collects the referenced loaded types.
+func (g *Generator) FieldSchema(f astparser.JSONField, refs *[]*astparser.TypeNode) *Schema {
+	s := g.typeSchema(f.Struct, f.Field.FieldType, refs)
+	if _, ok := f.Field.FieldType.(astparser.TypePointer); f.Nullable || ok && !f.Omitempty {
+		s = Nullable(s)
+	}
+
+	c := *s
+	if d := description(f.Field.Comments); d != "" {
+		c.Description = d
+	}
+	if format, ok := f.Field.AllTags["format"]; ok {
+		c.Format = format
+	}
+	if example, ok := f.Field.AllTags["example"]; ok {
+		c.Examples = []interface{}{jsonValue(exampleTypes(s), example)}
+	}
+	return &c
+}
+
+// exampleTypes returns JSON types of the field schema, nullable references
+// are expected to be objects.
+func exampleTypes(s *Schema) Types {
+	switch {
+	case len(s.Type) > 0:
+		return s.Type
+	case s.Ref != "" || len(s.AnyOf) > 0:
+		return Types{"object"}
+	default:
+		return nil
+	}
+}
+
+// typeSchema returns the schema of the type referenced by the declaration of n.
+func (g *Generator) typeSchema(n *astparser.TypeNode, t astparser.Type, refs *[]*astparser.TypeNode) *Schema {
+	switch t := t.(type) {
+	case astparser.TypeSimple:
+		return g.simpleSchema(t.Name)
+	case astparser.TypeArray:
+		if genutil.IsBytes(t) {
+			return &Schema{Type: Types{"string"}, ContentEncoding: "base64"}
+		}
+		return &Schema{Type: Types{"array"}, Items: g.typeSchema(n, t.InnerType, refs)}
+	case astparser.TypeMap:
+		return &Schema{Type: Types{"object"}, AdditionalProperties: g.typeSchema(n, t.ValueType, refs)}
+	case astparser.TypePointer:
+		return g.typeSchema(n, t.InnerType, refs)
+	case astparser.TypeCustom:
+		id := g.model.TypeID(n, t)
+		if s, ok := g.opts.Types[id]; ok {
+			return s
+		}
+		if s, ok := wellKnown[id]; ok {
+			return &s
+		}
+		if ref, ok := g.model.Graph().Node(id); ok {
+			*refs = append(*refs, ref)
+			return &Schema{Ref: g.opts.RefPrefix + g.names[id]}
+		}
+		return g.simpleSchema(id)
+	default:
+		// interfaces, inline structs and unknown types match anything.
+		return &Schema{}
+	}
+}
+
+// simpleSchema returns the schema of predeclared types, empty schema for others.
+func (g *Generator) simpleSchema(name string) *Schema {
+	var s Schema
+	switch name {
+	case "string":
+		s.Type = Types{"string"}
+	case "bool":
+		s.Type = Types{"boolean"}
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "byte", "rune", "uintptr":
+		s.Type = Types{"integer"}
+	case "float32", "float64":
+		s.Type = Types{"number"}
+	default:
+		return &s
+	}
+	s.Format = g.opts.Formats[name]
+	return &s
+}
+
+// jsonValue converts the tag or constant value to the JSON type, objects and arrays
+// are expected to be JSON encoded. Invalid values are kept as strings.
+func jsonValue(types Types, value string) interface{} {
+	if len(types) == 0 {
+		return value
+	}
+	switch types[0] {
+	case "object", "array":
+		var v interface{}
+		if err := json.Unmarshal([]byte(value), &v); err == nil {
+			return v
+		}
+	case "integer":
+		if v, err := strconv.ParseInt(value, 0, 64); err == nil {
+			return v
+		}
+	case "number":
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			return v
+		}
+	case "boolean":
+		if v, err := strconv.ParseBool(value); err == nil {
+			return v
+		}
+	}
+	return value
+}
+
+// description joins doc comment lines skipping annotations like `+enum`.
+func description(comments []string) string {
+	return strings.TrimSpace(strings.Join(genutil.DocLines(comments), "\n"))
+}
@@ -0,0 +1,100 @@
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/mkorolyov/astparser/gen/internal/gentest"
+)
+
+func assertJSON(t *testing.T, v interface{}, want string) {
+	t.Helper()
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(want), "", "  "); err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != buf.String() {
+		t.Errorf("\nhave %s, \nwant %s", data, buf.String())
+	}
+}
+
+func TestGenerator_Schema(t *testing.T) {
+	g := New(gentest.Model(t, gentest.Sources()), Options{})
+
+	s, err := g.Schema("Event")
+	if err != nil {
+		t.Fatalf("Schema() error = %v", err)
+	}
+	assertJSON(t, s, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$ref": "#/$defs/Event",
+		"$defs": {
+			"Event": {
+				"description": "Event is a user action.",
+				"type": "object",
+				"properties": {
+					"id": {"description": "ID is the event id.", "type": "integer", "examples": [42]},
+					"kind": {"$ref": "#/$defs/Kind", "description": "Kind is the event kind."},
+					"at": {"type": "string", "format": "date-time"},
+					"user": {"anyOf": [{"$ref": "#/$defs/User"}, {"type": "null"}]},
+					"email": {"type": "string", "format": "email", "examples": ["a@b.c"]},
+					"payload": {"type": "string", "contentEncoding": "base64"},
+					"labels": {"type": ["object", "null"], "additionalProperties": {"type": "string"}},
+					"scores": {"type": "object", "additionalProperties": {"type": "number"}},
+					"content-levels": {"type": "array", "items": {"$ref": "#/$defs/Level"}},
+					"sizes": {"type": "array", "items": {"type": "integer"}, "examples": [[1, 2]]},
+					"count": {"type": "integer"},
+					"rate": {"type": "number"},
+					"parent": {"anyOf": [{"$ref": "#/$defs/Event"}, {"type": "null"}]},
+					"trace": {"type": "string", "format": "uuid"},
+					"userId": {"$ref": "#/$defs/UserID"},
+					"priority": {"$ref": "#/$defs/Priority"},
+					"Extra": {}
+				},
+				"required": ["id", "kind", "at", "user", "email", "scores", "content-levels", "sizes", "parent", "trace", "userId", "priority", "Extra"]
+			},
+			"Kind": {"description": "Kind is the event kind.\n\nKinds are stored as strings.", "type": "string", "enum": ["created", "deleted"]},
+			"Level": {"type": "integer", "enum": [1, 2]},
+			"Priority": {"type": "integer", "enum": [1, 2]},
+			"User": {"type": "object", "properties": {"name": {"type": "string"}}, "required": ["name"]},
+			"UserID": {"type": "string"}
+		}
+	}`)
+}
+
+func TestGenerator_Definitions(t *testing.T) {
+	g := New(gentest.Model(t, gentest.Sources()), Options{
+		RefPrefix: "#/components/schemas/",
+		Types:     map[string]*Schema{"time.Time": {Type: Types{"integer"}}},
+	})
+
+	defs, err := g.Definitions("example.com/app/models.Level", "User")
+	if err != nil {
+		t.Fatalf("Definitions() error = %v", err)
+	}
+	assertJSON(t, defs, `{
+		"Level": {"type": "integer", "enum": [1, 2]},
+		"User": {"type": "object", "properties": {"name": {"type": "string"}}, "required": ["name"]}
+	}`)
+
+	defs, err = g.Definitions("Event")
+	if err != nil {
+		t.Fatalf("Definitions() error = %v", err)
+	}
+	assertJSON(t, defs["Event"].Property("at"), `{"type": "integer"}`)
+	assertJSON(t, defs["Event"].Property("user"), `{"anyOf": [{"$ref": "#/components/schemas/User"}, {"type": "null"}]}`)
+
+	if _, err := g.Definitions("Missing"); err == nil {
+		t.Error("expected not found error")
+	}
+}
+
+func TestNullable(t *testing.T) {
+	assertJSON(t, Nullable(&Schema{Type: Types{"string"}, Enum: []interface{}{"a"}}), `{"type": ["string", "null"], "enum": ["a", null]}`)
+	assertJSON(t, Nullable(&Schema{}), `{}`)
+}
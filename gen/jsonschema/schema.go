@@ -0,0 +1,101 @@
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Draft is the JSON Schema dialect of generated schemas.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema subset the generator produces.
+// Empty schema `{}` matches any value.
+type Schema struct {
+	Schema          string        `json:"$schema,omitempty"`
+	ID              string        `json:"$id,omitempty"`
+	Ref             string        `json:"$ref,omitempty"`
+	Title           string        `json:"title,omitempty"`
+	Description     string        `json:"description,omitempty"`
+	Type            Types         `json:"type,omitempty"`
+	Format          string        `json:"format,omitempty"`
+	ContentEncoding string        `json:"contentEncoding,omitempty"`
+	Enum            []interface{} `json:"enum,omitempty"`
+	Properties      Properties    `json:"properties,omitempty"`
+	Required        []string      `json:"required,omitempty"`
+	Items           *Schema       `json:"items,omitempty"`
+	// AdditionalProperties is the schema of map values.
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Examples             []interface{}      `json:"examples,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+}
+
+// Types are JSON types of a schema, a single type is encoded as a string.
+type Types []string
+
+func (t Types) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// Property is a named object property schema.
+type Property struct {
+	Name   string
+	Schema *Schema
+}
+
+// Properties are object properties encoded in the struct fields order.
+type Properties []Property
+
+func (p Properties) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, prop := range p {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(prop.Name)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		schema, err := json.Marshal(prop.Schema)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(schema)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Property returns the property schema by name, nil if there is no such property.
+func (s *Schema) Property(name string) *Schema {
+	for _, p := range s.Properties {
+		if p.Name == name {
+			return p.Schema
+		}
+	}
+	return nil
+}
+
+// Nullable returns the schema also matching null: the null type is added
+// to typed schemas and their enums, references are wrapped in anyOf.
+func Nullable(s *Schema) *Schema {
+	switch {
+	case s.Ref != "":
+		return &Schema{AnyOf: []*Schema{s, {Type: Types{"null"}}}}
+	case len(s.Type) > 0:
+		c := *s
+		c.Type = append(append(Types(nil), s.Type...), "null")
+		if len(s.Enum) > 0 {
+			c.Enum = append(append([]interface{}(nil), s.Enum...), nil)
+		}
+		return &c
+	default:
+		return s
+	}
+}
@@ -0,0 +1,90 @@
+// Package openapi generates OpenAPI 3.1 documents with components.schemas
+// of the model types.
+//
+// OpenAPI 3.1 schemas are JSON Schema Draft 2020-12 ones, so schemas are
+// generated by the jsonschema package with OpenAPI formats of numbers,
+// nullable values have the null type instead of the 3.0 `nullable` keyword.
+package openapi
+
+import (
+	"github.com/mkorolyov/astparser"
+	"github.com/mkorolyov/astparser/gen/jsonschema"
+)
+
+// Version is the OpenAPI version of generated documents.
+const Version = "3.1.0"
+
+// RefPrefix is the prefix of component schemas references.
+const RefPrefix = "#/components/schemas/"
+
+// formats are OpenAPI formats of predeclared types, unsigned integers have none.
+var formats = map[string]string{
+	"int":     "int64",
+	"int32":   "int32",
+	"int64":   "int64",
+	"rune":    "int32",
+	"float32": "float",
+	"float64": "double",
+}
+
+// Document is an OpenAPI document without paths.
+type Document struct {
+	OpenAPI    string     `json:"openapi"`
+	Info       Info       `json:"info"`
+	Components Components `json:"components"`
+}
+
+// Info is the document metadata.
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Components are reusable objects of the document.
+type Components struct {
+	Schemas map[string]*jsonschema.Schema `json:"schemas"`
+}
+
+// Options configure the generator.
+type Options struct {
+	// Info is the document info, the title is `API` and the version is `1.0.0` by default.
+	Info Info
+	// Types override schemas of the types by ID like `github.com/google/uuid.UUID`.
+	Types map[string]*jsonschema.Schema
+}
+
+// Generator generates OpenAPI documents of the model types.
+type Generator struct {
+	schemas *jsonschema.Generator
+	info    Info
+}
+
+// New creates a generator of the model types.
+func New(model *astparser.Model, opts Options) *Generator {
+	if opts.Info.Title == "" {
+		opts.Info.Title = "API"
+	}
+	if opts.Info.Version == "" {
+		opts.Info.Version = "1.0.0"
+	}
+	return &Generator{
+		schemas: jsonschema.New(model, jsonschema.Options{RefPrefix: RefPrefix, Types: opts.Types, Formats: formats}),
+		info:    opts.Info,
+	}
+}
+
+// Schemas returns component schemas by name of the types found by ID or unique name,
+// and of all the types they reference.
+func (g *Generator) Schemas(roots ...string) (map[string]*jsonschema.Schema, error) {
+	return g.schemas.Definitions(roots...)
+}
+
+// Document returns the document with component schemas of the types
+// and of all the types they reference.
+func (g *Generator) Document(roots ...string) (*Document, error) {
+	schemas, err := g.Schemas(roots...)
+	if err != nil {
+		return nil, err
+	}
+	return &Document{OpenAPI: Version, Info: g.info, Components: Components{Schemas: schemas}}, nil
+}
@@ -0,0 +1,78 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/mkorolyov/astparser/gen/internal/gentest"
+	"github.com/mkorolyov/astparser/gen/jsonschema"
+)
+
+func assertJSON(t *testing.T, v interface{}, want string) {
+	t.Helper()
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(want), "", "  "); err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != buf.String() {
+		t.Errorf("\nhave %s, \nwant %s", data, buf.String())
+	}
+}
+
+func TestGenerator_Document(t *testing.T) {
+	g := New(gentest.Model(t, gentest.Sources()), Options{
+		Info:  Info{Title: "Events"},
+		Types: map[string]*jsonschema.Schema{"time.Time": {Type: jsonschema.Types{"string"}, Format: "date"}},
+	})
+
+	doc, err := g.Document("Event")
+	if err != nil {
+		t.Fatalf("Document() error = %v", err)
+	}
+	assertJSON(t, doc, `{
+		"openapi": "3.1.0",
+		"info": {"title": "Events", "version": "1.0.0"},
+		"components": {
+			"schemas": {
+				"Event": {
+					"description": "Event is a user action.",
+					"type": "object",
+					"properties": {
+						"id": {"description": "ID is the event id.", "type": "integer", "format": "int64", "examples": [42]},
+						"kind": {"$ref": "#/components/schemas/Kind", "description": "Kind is the event kind."},
+						"at": {"type": "string", "format": "date"},
+						"user": {"anyOf": [{"$ref": "#/components/schemas/User"}, {"type": "null"}]},
+						"email": {"type": "string", "format": "email", "examples": ["a@b.c"]},
+						"payload": {"type": "string", "contentEncoding": "base64"},
+						"labels": {"type": ["object", "null"], "additionalProperties": {"type": "string"}},
+						"scores": {"type": "object", "additionalProperties": {"type": "number", "format": "double"}},
+						"content-levels": {"type": "array", "items": {"$ref": "#/components/schemas/Level"}},
+						"sizes": {"type": "array", "items": {"type": "integer", "format": "int64"}, "examples": [[1, 2]]},
+						"count": {"type": "integer", "format": "int32"},
+						"rate": {"type": "number", "format": "float"},
+						"parent": {"anyOf": [{"$ref": "#/components/schemas/Event"}, {"type": "null"}]},
+						"trace": {"type": "string", "format": "uuid"},
+						"userId": {"$ref": "#/components/schemas/UserID"},
+						"priority": {"$ref": "#/components/schemas/Priority"},
+						"Extra": {}
+					},
+					"required": ["id", "kind", "at", "user", "email", "scores", "content-levels", "sizes", "parent", "trace", "userId", "priority", "Extra"]
+				},
+				"Kind": {"description": "Kind is the event kind.\n\nKinds are stored as strings.", "type": "string", "enum": ["created", "deleted"]},
+				"Level": {"type": "integer", "format": "int64", "enum": [1, 2]},
+				"Priority": {"type": "integer", "enum": [1, 2]},
+				"User": {"type": "object", "properties": {"name": {"type": "string"}}, "required": ["name"]},
+				"UserID": {"type": "string"}
+			}
+		}
+	}`)
+
+	if _, err := g.Document("Missing"); err == nil {
+		t.Error("expected not found error")
+	}
+}
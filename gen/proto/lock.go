@@ -0,0 +1,167 @@
+package proto
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Lock persists field numbers of messages and value numbers of string enums,
+// so regenerations never renumber existing fields. Numbers of removed fields
+// are kept and reserved. Messages and enums are keyed by type ID like
+// `example.com/app/models.Event`, so they keep numbers when their proto names
+// are qualified because of a name collision.
+type Lock struct {
+	// Messages are field numbers by proto field name by message type ID.
+	Messages map[string]map[string]int `json:"messages"`
+	// Enums are value numbers by value name without the enum prefix by enum type ID.
+	Enums map[string]map[string]int `json:"enums"`
+}
+
+// NewLock returns an empty lock.
+func NewLock() *Lock {
+	return &Lock{Messages: map[string]map[string]int{}, Enums: map[string]map[string]int{}}
+}
+
+// ReadLock reads the lock file, a missing file gives an empty lock.
+func ReadLock(path string) (*Lock, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return NewLock(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	l := NewLock()
+	if err := json.Unmarshal(data, l); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode lock file %s", path)
+	}
+	if l.Messages == nil {
+		l.Messages = map[string]map[string]int{}
+	}
+	if l.Enums == nil {
+		l.Enums = map[string]map[string]int{}
+	}
+	return l, nil
+}
+
+// WriteFile writes the lock file.
+func (l *Lock) WriteFile(path string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// numbering assigns numbers to names: explicitly set ones are kept, locked
+// ones are reused and new names get the free numbers following the locked ones. Numbers of the
+// locked names which are not used anymore are never reused.
+type numbering struct {
+	locked   map[string]int
+	numbers  map[string]int
+	used     map[int]string
+	next     int
+	reserved func(n int) bool
+}
+
+func newNumbering(locked map[string]int, first int, reserved func(n int) bool) *numbering {
+	n := &numbering{locked: locked, numbers: map[string]int{}, used: map[int]string{}, next: first, reserved: reserved}
+	for name, number := range locked {
+		n.used[number] = name
+		if number >= n.next {
+			n.next = number + 1
+		}
+	}
+	return n
+}
+
+// set assigns the explicit number, it fails if the number is used by another name
+// or the name is locked with another number.
+func (n *numbering) set(name string, number int) error {
+	if other, ok := n.used[number]; ok && other != name {
+		return errors.Errorf("number %d of %s is used by %s", number, name, other)
+	}
+	if locked, ok := n.locked[name]; ok && locked != number {
+		return errors.Errorf("number %d of %s is locked as %d", number, name, locked)
+	}
+	n.used[number] = name
+	n.numbers[name] = number
+	return nil
+}
+
+// assign returns the locked or the next free number of the name.
+func (n *numbering) assign(name string) int {
+	if number, ok := n.numbers[name]; ok {
+		return number
+	}
+	if number, ok := n.locked[name]; ok {
+		n.numbers[name] = number
+		return number
+	}
+	for n.used[n.next] != "" || n.reserved(n.next) {
+		n.next++
+	}
+	number := n.next
+	n.used[number] = name
+	n.numbers[name] = number
+	return number
+}
+
+// lock returns the numbers to persist: the assigned ones and the ones of removed names.
+func (n *numbering) lock() map[string]int {
+	numbers := map[string]int{}
+	for number, name := range n.used {
+		numbers[name] = number
+	}
+	return numbers
+}
+
+// removed returns sorted numbers and names which are locked but not assigned.
+func (n *numbering) removed() ([]int, []string) {
+	var numbers []int
+	var names []string
+	for number, name := range n.used {
+		if _, ok := n.numbers[name]; !ok {
+			numbers = append(numbers, number)
+			names = append(names, name)
+		}
+	}
+	sort.Ints(numbers)
+	sort.Strings(names)
+	return numbers, names
+}
+
+// tagNumber returns the field number of the `protobuf:"3"` tag or the tag
+// golang/protobuf generates like `protobuf:"bytes,3,opt,name=id"`.
+func tagNumber(tag string) (int, bool, error) {
+	if tag == "" {
+		return 0, false, nil
+	}
+	parts := strings.Split(tag, ",")
+	value := parts[0]
+	if len(parts) > 1 {
+		value = parts[1]
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 1 || number > maxFieldNumber {
+		return 0, false, errors.Errorf("invalid protobuf tag %q", tag)
+	}
+	if isReservedNumber(number) {
+		return 0, false, errors.Errorf("protobuf tag %q uses reserved number", tag)
+	}
+	return number, true, nil
+}
+
+const maxFieldNumber = 1<<29 - 1
+
+// isReservedNumber reports whether the field number is reserved by protobuf implementation.
+func isReservedNumber(n int) bool {
+	return n >= 19000 && n <= 19999
+}
@@ -0,0 +1,429 @@
+// Package proto generates proto3 schemas of the model types.
+//
+// Structs are messages with fields named by JSONName in snake case, embedded
+// structs are flattened. Named types with typed constants are enums, other
+// named types are replaced by their underlying types. Field numbers are set by
+// `protobuf:"3"` tags or persisted in a Lock, so regenerations never renumber
+// existing fields and numbers of removed fields are reserved.
+package proto
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/mkorolyov/astparser"
+	"github.com/mkorolyov/astparser/gen/internal/genutil"
+)
+
+// Header is the first line of generated files.
+const Header = "// Code generated by astparser. DO NOT EDIT."
+
+// Options configure the generator.
+type Options struct {
+	// Package is the proto package like `events.v1`.
+	Package string
+	// GoPackage is the `go_package` file option, not set if empty.
+	GoPackage string
+	// Types map type IDs like `github.com/google/uuid.UUID` to proto types.
+	Types map[string]string
+	// Lock keeps field numbers, it is updated by Generate. Without lock
+	// fields not numbered by tags are numbered in order.
+	Lock *Lock
+}
+
+// Generator generates proto files of the model types.
+type Generator struct {
+	model *astparser.Model
+	opts  Options
+	// names are message and enum names by type ID.
+	names map[string]string
+	// imports are files imported by the well-known types used.
+	imports map[string]bool
+	// resolving are named types being resolved to catch recursive types.
+	resolving map[string]bool
+}
+
+// wellKnownType is a proto type the common Go types map to and the file declaring it.
+type wellKnownType struct {
+	name string
+	file string
+}
+
+var wellKnown = map[string]wellKnownType{
+	genutil.Time:       {name: "google.protobuf.Timestamp", file: "google/protobuf/timestamp.proto"},
+	genutil.Duration:   {name: "google.protobuf.Duration", file: "google/protobuf/duration.proto"},
+	genutil.RawMessage: {name: "google.protobuf.Value", file: "google/protobuf/struct.proto"},
+}
+
+var scalars = map[string]string{
+	"string":  "string",
+	"bool":    "bool",
+	"int":     "int64",
+	"int8":    "int32",
+	"int16":   "int32",
+	"int32":   "int32",
+	"rune":    "int32",
+	"int64":   "int64",
+	"uint":    "uint64",
+	"uint8":   "uint32",
+	"byte":    "uint32",
+	"uint16":  "uint32",
+	"uint32":  "uint32",
+	"uint64":  "uint64",
+	"uintptr": "uint64",
+	"float32": "float",
+	"float64": "double",
+}
+
+// mapKeys are scalar types allowed as map keys.
+var mapKeys = map[string]bool{
+	"string": true, "bool": true, "int32": true, "int64": true, "uint32": true, "uint64": true,
+}
+
+// New creates a generator of the model types.
+func New(model *astparser.Model, opts Options) *Generator {
+	if opts.Lock == nil {
+		opts.Lock = NewLock()
+	}
+	if opts.Lock.Messages == nil {
+		opts.Lock.Messages = map[string]map[string]int{}
+	}
+	if opts.Lock.Enums == nil {
+		opts.Lock.Enums = map[string]map[string]int{}
+	}
+	return &Generator{model: model, opts: opts, names: genutil.Names(model, genutil.PackageTypeName)}
+}
+
+// Generate writes the proto file with messages and enums of the types found by ID
+// or unique name and of all the types they reference, sorted by type ID.
+func (g *Generator) Generate(w io.Writer, roots ...string) error {
+	ids := make([]string, len(roots))
+	for i, root := range roots {
+		id, err := g.model.Graph().Find(root)
+		if err != nil {
+			return err
+		}
+		ids[i] = id
+	}
+
+	g.imports = map[string]bool{}
+	g.resolving = map[string]bool{}
+	var body bytes.Buffer
+	for _, id := range g.model.Graph().Reachable(ids...) {
+		n, _ := g.model.Graph().Node(id)
+		var err error
+		switch {
+		case n.Struct != nil:
+			err = g.message(&body, n)
+		case len(g.model.Constants(n)) > 0:
+			err = g.enum(&body, n)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(Header + "\n\n")
+	buf.WriteString("syntax = \"proto3\";\n")
+	if g.opts.Package != "" {
+		fmt.Fprintf(&buf, "\npackage %s;\n", g.opts.Package)
+	}
+	if len(g.imports) > 0 {
+		buf.WriteByte('\n')
+		for _, i := range sortedKeys(g.imports) {
+			fmt.Fprintf(&buf, "import %q;\n", i)
+		}
+	}
+	if g.opts.GoPackage != "" {
+		fmt.Fprintf(&buf, "\noption go_package = %q;\n", g.opts.GoPackage)
+	}
+	buf.Write(body.Bytes())
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// message writes the struct message numbering its fields.
+func (g *Generator) message(buf *bytes.Buffer, n *astparser.TypeNode) error {
+	name := g.names[n.ID]
+	fields := g.model.JSONFields(n)
+	numbers := newNumbering(g.opts.Lock.Messages[n.ID], 1, isReservedNumber)
+
+	names := make([]string, len(fields))
+	// JSON names by field names, `userId` and `user_id` are both `user_id`.
+	jsonNames := map[string]string{}
+	for i, f := range fields {
+		names[i] = fieldName(f.Name)
+		if other, ok := jsonNames[names[i]]; ok {
+			return fmt.Errorf("message %s: fields %s and %s have the same name %s", name, other, f.Name, names[i])
+		}
+		jsonNames[names[i]] = f.Name
+		number, ok, err := tagNumber(f.Field.AllTags["protobuf"])
+		if err != nil {
+			return fmt.Errorf("message %s field %s: %v", name, names[i], err)
+		}
+		if !ok {
+			continue
+		}
+		if err := numbers.set(names[i], number); err != nil {
+			return fmt.Errorf("message %s: %v", name, err)
+		}
+	}
+
+	buf.WriteByte('\n')
+	writeComments(buf, "", n.Comments())
+	fmt.Fprintf(buf, "message %s {\n", name)
+	for i, f := range fields {
+		t, err := g.fieldType(f)
+		if err != nil {
+			return fmt.Errorf("message %s field %s: %v", name, names[i], err)
+		}
+		writeComments(buf, "  ", f.Field.Comments)
+		fmt.Fprintf(buf, "  %s %s = %d;\n", t, names[i], numbers.assign(names[i]))
+	}
+	writeReserved(buf, numbers, "")
+	buf.WriteString("}\n")
+
+	g.opts.Lock.Messages[n.ID] = numbers.lock()
+	return nil
+}
+
+// enum writes the enum of the type constants. Integer constants keep their values,
+// string ones are numbered by the lock. The zero value is added if there is none.
+func (g *Generator) enum(buf *bytes.Buffer, n *astparser.TypeNode) error {
+	name := g.names[n.ID]
+	prefix := upperSnake(name) + "_"
+	constants := g.model.Constants(n)
+
+	simple, _ := n.Type.Type.(astparser.TypeSimple)
+	integer := strings.Contains(scalars[simple.Name], "int")
+
+	numbers := newNumbering(g.opts.Lock.Enums[n.ID], 1, func(int) bool { return false })
+	type value struct {
+		name   string
+		number int
+	}
+	values := make([]value, len(constants))
+	hasZero, aliases := false, false
+	seen := map[int]bool{}
+	for i, c := range constants {
+		// values are locked without the prefix which changes with the enum name.
+		valueName := upperSnake(enumValueName(c.Name, n.Name))
+		values[i].name = prefix + valueName
+		if !integer {
+			values[i].number = numbers.assign(valueName)
+			continue
+		}
+
+		v, err := strconv.ParseInt(c.Value, 0, 32)
+		if err != nil {
+			return fmt.Errorf("enum %s value %s: %v", name, c.Name, err)
+		}
+		values[i].number = int(v)
+		hasZero = hasZero || v == 0
+		aliases = aliases || seen[int(v)]
+		seen[int(v)] = true
+	}
+	if !hasZero {
+		values = append([]value{{name: prefix + "UNSPECIFIED"}}, values...)
+	}
+	// proto3 requires the zero value to be the first one.
+	sort.SliceStable(values, func(i, j int) bool {
+		if zi, zj := values[i].number == 0, values[j].number == 0; zi != zj {
+			return zi
+		}
+		return values[i].number < values[j].number
+	})
+
+	buf.WriteByte('\n')
+	writeComments(buf, "", n.Comments())
+	fmt.Fprintf(buf, "enum %s {\n", name)
+	if aliases {
+		buf.WriteString("  option allow_alias = true;\n")
+	}
+	for _, v := range values {
+		fmt.Fprintf(buf, "  %s = %d;\n", v.name, v.number)
+	}
+	if !integer {
+		writeReserved(buf, numbers, prefix)
+		g.opts.Lock.Enums[n.ID] = numbers.lock()
+	}
+	buf.WriteString("}\n")
+	return nil
+}
+
+// fieldType returns the field type with the repeated or optional label.
+func (g *Generator) fieldType(f astparser.JSONField) (string, error) {
+	t, repeated, err := g.protoType(f.Struct, f.Field.FieldType)
+	if err != nil {
+		return "", err
+	}
+	if repeated {
+		return "repeated " + t, nil
+	}
+	// pointers to scalars keep presence.
+	if _, ok := f.Field.FieldType.(astparser.TypePointer); ok && (isScalar(t) || t == "bytes") {
+		return "optional " + t, nil
+	}
+	return t, nil
+}
+
+func isScalar(t string) bool {
+	for _, s := range scalars {
+		if s == t {
+			return true
+		}
+	}
+	return false
+}
+
+// protoType returns the proto type of the type referenced by the declaration of n,
+// repeated is true for slices.
+func (g *Generator) protoType(n *astparser.TypeNode, t astparser.Type) (string, bool, error) {
+	switch t := t.(type) {
+	case astparser.TypeSimple:
+		return scalars[t.Name], false, nil
+	case astparser.TypeArray:
+		if genutil.IsBytes(t) {
+			return "bytes", false, nil
+		}
+		inner, repeated, err := g.protoType(n, t.InnerType)
+		if err != nil {
+			return "", false, err
+		}
+		if repeated || strings.HasPrefix(inner, "map<") {
+			return "", false, fmt.Errorf("slices of %s are not supported", t.InnerType)
+		}
+		return inner, true, nil
+	case astparser.TypeMap:
+		key, repeated, err := g.protoType(n, t.KeyType)
+		if err != nil {
+			return "", false, err
+		}
+		if repeated || !mapKeys[key] {
+			return "", false, fmt.Errorf("map keys of %s are not supported", t.KeyType)
+		}
+		value, repeated, err := g.protoType(n, t.ValueType)
+		if err != nil {
+			return "", false, err
+		}
+		if repeated || strings.HasPrefix(value, "map<") {
+			return "", false, fmt.Errorf("map values of %s are not supported", t.ValueType)
+		}
+		return "map<" + key + ", " + value + ">", false, nil
+	case astparser.TypePointer:
+		return g.protoType(n, t.InnerType)
+	case astparser.TypeCustom:
+		return g.customType(n, t)
+	case astparser.TypeInterfaceValue:
+		g.imports["google/protobuf/struct.proto"] = true
+		return "google.protobuf.Value", false, nil
+	default:
+		return "", false, fmt.Errorf("type %v is not supported", t)
+	}
+}
+
+func (g *Generator) customType(n *astparser.TypeNode, t astparser.TypeCustom) (string, bool, error) {
+	id := g.model.TypeID(n, t)
+	if pt, ok := g.opts.Types[id]; ok {
+		return pt, false, nil
+	}
+	if wk, ok := wellKnown[id]; ok {
+		g.imports[wk.file] = true
+		return wk.name, false, nil
+	}
+	if s, ok := scalars[id]; ok {
+		return s, false, nil
+	}
+
+	ref, ok := g.model.Graph().Node(id)
+	if !ok {
+		return "", false, fmt.Errorf("type %s is not loaded, map it with options", id)
+	}
+	if ref.Struct != nil || len(g.model.Constants(ref)) > 0 {
+		return g.names[id], false, nil
+	}
+
+	// proto has no named types, they are replaced by the underlying ones.
+	if g.resolving[id] {
+		return "", false, fmt.Errorf("recursive type %s is not supported", id)
+	}
+	g.resolving[id] = true
+	defer delete(g.resolving, id)
+	return g.protoType(ref, ref.Type.Type)
+}
+
+// writeReserved writes reserved numbers and prefixed names of the removed fields.
+func writeReserved(buf *bytes.Buffer, numbers *numbering, prefix string) {
+	removed, names := numbers.removed()
+	if len(removed) == 0 {
+		return
+	}
+	list := make([]string, len(removed))
+	for i, r := range removed {
+		list[i] = strconv.Itoa(r)
+	}
+	fmt.Fprintf(buf, "  reserved %s;\n", strings.Join(list, ", "))
+	for i, name := range names {
+		names[i] = strconv.Quote(prefix + name)
+	}
+	fmt.Fprintf(buf, "  reserved %s;\n", strings.Join(names, ", "))
+}
+
+// writeComments writes doc comment lines skipping annotations like `+enum`.
+func writeComments(buf *bytes.Buffer, indent string, comments []string) {
+	for _, l := range genutil.DocLines(comments) {
+		fmt.Fprintf(buf, "%s%s\n", indent, strings.TrimSpace("// "+l))
+	}
+}
+
+// fieldName converts JSON names like `createdAt` or `content-type` to snake case.
+func fieldName(name string) string {
+	return strings.ToLower(upperSnake(name))
+}
+
+// enumValueName trims the type name prefix of the constant like `KindCreated` of `Kind`.
+func enumValueName(constant, typeName string) string {
+	if v := strings.TrimPrefix(constant, typeName); v != "" && v != constant {
+		return strings.TrimPrefix(v, "_")
+	}
+	return constant
+}
+
+// upperSnake converts names like `HTTPServer` or `createdAt` to `HTTP_SERVER` and `CREATED_AT`.
+func upperSnake(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if b.Len() > 0 && !strings.HasSuffix(b.String(), "_") {
+				b.WriteByte('_')
+			}
+			continue
+		}
+		if unicode.IsUpper(r) && i > 0 && b.Len() > 0 && !strings.HasSuffix(b.String(), "_") {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || unicode.IsUpper(prev) && nextLower {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return strings.TrimSuffix(b.String(), "_")
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
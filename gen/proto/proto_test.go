@@ -0,0 +1,281 @@
+package proto
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mkorolyov/astparser/gen/internal/gentest"
+)
+
+func generate(t *testing.T, source string, lock *Lock) (string, error) {
+	t.Helper()
+	sources := gentest.Sources()
+	sources["models/event.go"] = []byte(source)
+	return generateSources(t, sources, lock)
+}
+
+// generateSources generates Event of the sources module.
+func generateSources(t *testing.T, sources map[string][]byte, lock *Lock) (string, error) {
+	t.Helper()
+	g := New(gentest.Model(t, sources), Options{
+		Package:   "events.v1",
+		GoPackage: "example.com/app/eventspb",
+		Types:     map[string]string{"github.com/google/uuid.UUID": "string"},
+		Lock:      lock,
+	})
+
+	var buf bytes.Buffer
+	err := g.Generate(&buf, "example.com/app/models.Event")
+	return buf.String(), err
+}
+
+func TestGenerator_Generate(t *testing.T) {
+	lock := NewLock()
+	got, err := generate(t, gentest.EventSource, lock)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	want := `// Code generated by astparser. DO NOT EDIT.
+
+syntax = "proto3";
+
+package events.v1;
+
+import "google/protobuf/struct.proto";
+import "google/protobuf/timestamp.proto";
+
+option go_package = "example.com/app/eventspb";
+
+message User {
+  string name = 1;
+}
+
+message Base {
+  // ID is the event id.
+  int64 id = 1;
+}
+
+// Event is a user action.
+message Event {
+  // ID is the event id.
+  int64 id = 1;
+  // Kind is the event kind.
+  Kind kind = 2;
+  google.protobuf.Timestamp at = 3;
+  User user = 4;
+  string email = 5;
+  bytes payload = 6;
+  map<string, string> labels = 10;
+  map<string, double> scores = 7;
+  repeated Level content_levels = 8;
+  repeated int64 sizes = 9;
+  optional int32 count = 11;
+  optional float rate = 12;
+  Event parent = 13;
+  string trace = 14;
+  string user_id = 15;
+  Priority priority = 16;
+  google.protobuf.Value extra = 17;
+}
+
+// Kind is the event kind.
+//
+// Kinds are stored as strings.
+enum Kind {
+  KIND_UNSPECIFIED = 0;
+  KIND_CREATED = 1;
+  KIND_DELETED = 2;
+}
+
+enum Level {
+  LEVEL_UNSPECIFIED = 0;
+  LEVEL_LOW = 1;
+  LEVEL_HIGH = 2;
+}
+
+enum Priority {
+  PRIORITY_UNSPECIFIED = 0;
+  PRIORITY_LOW = 1;
+  PRIORITY_HIGH = 2;
+}
+`
+	if got != want {
+		t.Errorf("\nhave %s\nwant %s", got, want)
+	}
+
+	wantLock := map[string]int{
+		"id": 1, "kind": 2, "at": 3, "user": 4, "email": 5, "payload": 6, "labels": 10, "scores": 7, "content_levels": 8,
+		"sizes": 9, "count": 11, "rate": 12, "parent": 13, "trace": 14, "user_id": 15, "priority": 16, "extra": 17,
+	}
+	if !reflect.DeepEqual(lock.Messages["example.com/app/models.Event"], wantLock) {
+		t.Errorf("lock\nhave %+v, \nwant %+v", lock.Messages["example.com/app/models.Event"], wantLock)
+	}
+	if _, ok := lock.Enums["example.com/app/models.Level"]; ok {
+		t.Errorf("integer enums are not locked: %+v", lock.Enums)
+	}
+}
+
+func TestGenerator_GenerateLocked(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.proto.lock")
+	lock, err := ReadLock(path)
+	if err != nil {
+		t.Fatalf("ReadLock() error = %v", err)
+	}
+	if _, err := generate(t, gentest.EventSource, lock); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if err := lock.WriteFile(path); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	// payload and the deleted kind are removed, source and archived are added.
+	source := strings.Replace(gentest.EventSource, "Payload  []byte             `json:\"payload,omitempty\"`", "Source   string             `json:\"source\"`", 1)
+	source = strings.Replace(source, `KindDeleted Kind = "deleted"`, `KindArchived Kind = "archived"`, 1)
+	if lock, err = ReadLock(path); err != nil {
+		t.Fatalf("ReadLock() error = %v", err)
+	}
+	got, err := generate(t, source, lock)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	for _, want := range []string{
+		"  string email = 5;\n  string source = 18;\n  map<string, string> labels = 10;\n",
+		"  google.protobuf.Value extra = 17;\n  reserved 6;\n  reserved \"payload\";\n}",
+		"  KIND_CREATED = 1;\n  KIND_ARCHIVED = 3;\n  reserved 2;\n  reserved \"KIND_DELETED\";\n}",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output has no %q\n%s", want, got)
+		}
+	}
+	if lock.Messages["example.com/app/models.Event"]["payload"] != 6 || lock.Enums["example.com/app/models.Kind"]["DELETED"] != 2 {
+		t.Errorf("removed numbers are not locked: %+v", lock)
+	}
+}
+
+func TestGenerator_GenerateQualifiedNames(t *testing.T) {
+	lock := NewLock()
+	if _, err := generate(t, gentest.EventSource, lock); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	// another Event qualifies the names, the locked numbers are kept.
+	source := strings.Replace(gentest.EventSource, "Payload  []byte             `json:\"payload,omitempty\"`", "Source   string             `json:\"source\"`", 1)
+	source = strings.Replace(source, `KindCreated Kind = "created"`, `KindArchived Kind = "archived"`, 1)
+	got, err := generateSources(t, map[string][]byte{
+		"go.mod":          []byte("module example.com/app\n"),
+		"models/event.go": []byte(source),
+		"dto/user.go":     []byte(gentest.UserSource),
+		"audit/event.go":  []byte("package audit\n\ntype Event struct{}\n\ntype Kind string\n"),
+	}, lock)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	for _, want := range []string{
+		"message ModelsEvent {\n",
+		"  ModelsKind kind = 2;\n",
+		"  string email = 5;\n  string source = 18;\n",
+		"  reserved 6;\n  reserved \"payload\";\n}",
+		"  MODELS_KIND_DELETED = 2;\n  MODELS_KIND_ARCHIVED = 3;\n  reserved 1;\n  reserved \"MODELS_KIND_CREATED\";\n}",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output has no %q\n%s", want, got)
+		}
+	}
+}
+
+func TestGenerator_GenerateNegativeEnum(t *testing.T) {
+	source := strings.Replace(gentest.EventSource, "LevelLow  Level = 1", "LevelDebug Level = -1\n\tLevelLow  Level = 1", 1)
+	got, err := generate(t, source, nil)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	want := "enum Level {\n  LEVEL_UNSPECIFIED = 0;\n  LEVEL_DEBUG = -1;\n  LEVEL_LOW = 1;\n  LEVEL_HIGH = 2;\n}"
+	if !strings.Contains(got, want) {
+		t.Errorf("output has no %q\n%s", want, got)
+	}
+}
+
+func TestGenerator_GenerateErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		lock    *Lock
+		wantErr string
+	}{
+		{
+			name:    "duplicate tag",
+			source:  strings.Replace(gentest.EventSource, `protobuf:"bytes,10,rep,name=labels"`, `protobuf:"1"`, 1),
+			wantErr: "message Event: number 1 of labels is used by id",
+		},
+		{
+			name:    "field names collision",
+			source:  strings.Replace(gentest.EventSource, `json:"email"`, `json:"user_id"`, 1),
+			wantErr: "message Event: fields user_id and userId have the same name user_id",
+		},
+		{
+			name:    "tag conflicts with lock",
+			source:  gentest.EventSource,
+			lock:    &Lock{Messages: map[string]map[string]int{"example.com/app/models.Event": {"removed": 10}}},
+			wantErr: "message Event: number 10 of labels is used by removed",
+		},
+		{
+			name:    "invalid tag",
+			source:  strings.Replace(gentest.EventSource, `protobuf:"1"`, `protobuf:"19500"`, 1),
+			wantErr: "message Base field id: protobuf tag \"19500\" uses reserved number",
+		},
+		{
+			name:    "nested slices",
+			source:  strings.Replace(gentest.EventSource, "Levels   []*Level", "Levels   [][]Level", 1),
+			wantErr: "message Event field content_levels: slices of []Level are not supported",
+		},
+		{
+			name:    "unknown type",
+			source:  strings.Replace(gentest.EventSource, "Extra    interface{}", "Extra    json.Number", 1),
+			wantErr: "message Event field extra: type json.Number is not loaded, map it with options",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := generate(t, tt.source, tt.lock)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Generate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadLock_invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lock")
+	if err := ioutil.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadLock(path); err == nil || !strings.Contains(err.Error(), "failed to decode lock file") {
+		t.Errorf("ReadLock() error = %v", err)
+	}
+	os.Remove(path)
+}
+
+func Test_upperSnake(t *testing.T) {
+	tests := map[string]string{
+		"createdAt":    "CREATED_AT",
+		"HTTPServer":   "HTTP_SERVER",
+		"userID":       "USER_ID",
+		"content-type": "CONTENT_TYPE",
+		"v2Name":       "V2_NAME",
+		"ID":           "ID",
+	}
+	for name, want := range tests {
+		if got := upperSnake(name); got != want {
+			t.Errorf("upperSnake(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
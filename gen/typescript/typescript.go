@@ -0,0 +1,226 @@
+// Package typescript generates TypeScript declarations of the model types
+// as encoding/json encodes them.
+//
+// Structs are interfaces with properties named by JSONName, embedded structs
+// are flattened, omitempty fields are optional and `nullable:"true"` fields
+// or pointers without omitempty could be null. Named types with typed
+// constants are unions of the constant literals, other named types are aliases.
+package typescript
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mkorolyov/astparser"
+	"github.com/mkorolyov/astparser/gen/internal/genutil"
+)
+
+// Header is the first line of generated files.
+const Header = "// Code generated by astparser. DO NOT EDIT."
+
+// Options configure the generator.
+type Options struct {
+	// Types map type IDs like `github.com/google/uuid.UUID` to TypeScript types.
+	Types map[string]string
+}
+
+// Generator generates declarations of the model types.
+type Generator struct {
+	model *astparser.Model
+	types map[string]string
+	// names are declaration names by type ID.
+	names map[string]string
+}
+
+// wellKnown are TypeScript types of the common types encoded as JSON strings or numbers.
+var wellKnown = map[string]string{
+	genutil.Time:       "string",
+	genutil.Duration:   "number",
+	genutil.RawMessage: "unknown",
+	genutil.Number:     "number",
+	genutil.URL:        "string",
+	genutil.IP:         "string",
+	genutil.BigInt:     "number",
+	genutil.UUID:       "string",
+}
+
+// New creates a generator of the model types declarations.
+func New(model *astparser.Model, opts Options) *Generator {
+	types := make(map[string]string, len(wellKnown)+len(opts.Types))
+	for id, t := range wellKnown {
+		types[id] = t
+	}
+	for id, t := range opts.Types {
+		types[id] = t
+	}
+	return &Generator{model: model, types: types, names: genutil.Names(model, genutil.PackageTypeName)}
+}
+
+// Generate writes declarations of the types found by ID or unique name
+// and of all the types they reference, sorted by type ID.
+func (g *Generator) Generate(w io.Writer, roots ...string) error {
+	ids := make([]string, len(roots))
+	for i, root := range roots {
+		id, err := g.model.Graph().Find(root)
+		if err != nil {
+			return err
+		}
+		ids[i] = id
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(Header + "\n")
+	for _, id := range g.model.Graph().Reachable(ids...) {
+		n, _ := g.model.Graph().Node(id)
+		buf.WriteByte('\n')
+		g.declaration(&buf, n)
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// declaration writes the struct interface or the named type alias.
+func (g *Generator) declaration(buf *bytes.Buffer, n *astparser.TypeNode) {
+	writeDoc(buf, "", n.Comments())
+	name := g.names[n.ID]
+
+	if n.Struct == nil {
+		fmt.Fprintf(buf, "export type %s = %s;\n", name, g.namedType(n))
+		return
+	}
+
+	fmt.Fprintf(buf, "export interface %s {\n", name)
+	for _, f := range g.model.JSONFields(n) {
+		writeDoc(buf, "  ", f.Field.Comments)
+
+		t := g.typeName(f.Struct, f.Field.FieldType)
+		if _, ok := f.Field.FieldType.(astparser.TypePointer); f.Nullable || ok && !f.Omitempty {
+			t += " | null"
+		}
+		optional := ""
+		if f.Omitempty {
+			optional = "?"
+		}
+		fmt.Fprintf(buf, "  %s%s: %s;\n", propertyName(f.Name), optional, t)
+	}
+	buf.WriteString("}\n")
+}
+
+// namedType returns the union of the type constants literals, or its underlying type.
+func (g *Generator) namedType(n *astparser.TypeNode) string {
+	t := g.typeName(n, n.Type.Type)
+	constants := g.model.Constants(n)
+	if len(constants) == 0 {
+		return t
+	}
+
+	literals := make([]string, len(constants))
+	for i, c := range constants {
+		literals[i] = literal(t, c.Value)
+	}
+	return strings.Join(literals, " | ")
+}
+
+// typeName returns TypeScript type of the type referenced by the declaration of n.
+func (g *Generator) typeName(n *astparser.TypeNode, t astparser.Type) string {
+	switch t := t.(type) {
+	case astparser.TypeSimple:
+		return simpleType(t.Name)
+	case astparser.TypeArray:
+		if genutil.IsBytes(t) {
+			return "string"
+		}
+		inner := g.typeName(n, t.InnerType)
+		if strings.Contains(inner, " ") {
+			inner = "(" + inner + ")"
+		}
+		return inner + "[]"
+	case astparser.TypeMap:
+		// JSON object keys are strings whatever the map key type is.
+		return "Record<string, " + g.typeName(n, t.ValueType) + ">"
+	case astparser.TypePointer:
+		return g.typeName(n, t.InnerType)
+	case astparser.TypeCustom:
+		id := g.model.TypeID(n, t)
+		if ts, ok := g.types[id]; ok {
+			return ts
+		}
+		if _, ok := g.model.Graph().Node(id); ok {
+			return g.names[id]
+		}
+		return simpleType(id)
+	default:
+		// interfaces, inline structs and unknown types.
+		return "unknown"
+	}
+}
+
+func simpleType(name string) string {
+	switch name {
+	case "string":
+		return "string"
+	case "bool":
+		return "boolean"
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64",
+		"byte", "rune", "uintptr", "float32", "float64":
+		return "number"
+	default:
+		return "unknown"
+	}
+}
+
+// literal returns the constant value literal, quoted unless the type is number or boolean.
+func literal(t, value string) string {
+	switch t {
+	case "number":
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			return value
+		}
+		if v, err := strconv.ParseInt(value, 0, 64); err == nil {
+			return strconv.FormatInt(v, 10)
+		}
+	case "boolean":
+		if v, err := strconv.ParseBool(value); err == nil {
+			return strconv.FormatBool(v)
+		}
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// propertyName quotes JSON names which are not identifiers like `content-type`.
+func propertyName(name string) string {
+	if identifier.MatchString(name) {
+		return name
+	}
+	data, _ := json.Marshal(name)
+	return string(data)
+}
+
+// writeDoc writes doc comment lines skipping annotations like `+enum` as JSDoc.
+func writeDoc(buf *bytes.Buffer, indent string, comments []string) {
+	lines := genutil.DocLines(comments)
+	for i, l := range lines {
+		lines[i] = strings.ReplaceAll(l, "*/", "*\\/")
+	}
+
+	switch len(lines) {
+	case 0:
+	case 1:
+		fmt.Fprintf(buf, "%s/** %s */\n", indent, lines[0])
+	default:
+		fmt.Fprintf(buf, "%s/**\n", indent)
+		for _, l := range lines {
+			fmt.Fprintf(buf, "%s %s\n", indent, strings.TrimSpace("* "+l))
+		}
+		fmt.Fprintf(buf, "%s */\n", indent)
+	}
+}
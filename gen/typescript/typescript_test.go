@@ -0,0 +1,74 @@
+package typescript
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mkorolyov/astparser/gen/internal/gentest"
+)
+
+func TestGenerator_Generate(t *testing.T) {
+	g := New(gentest.Model(t, gentest.Sources()), Options{
+		Types: map[string]string{"github.com/google/uuid.UUID": "UUID | string"},
+	})
+
+	var buf bytes.Buffer
+	if err := g.Generate(&buf, "Event"); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	want := `// Code generated by astparser. DO NOT EDIT.
+
+export interface User {
+  name: string;
+}
+
+export interface Base {
+  /** ID is the event id. */
+  id: number;
+}
+
+/** Event is a user action. */
+export interface Event {
+  /** ID is the event id. */
+  id: number;
+  /** Kind is the event kind. */
+  kind: Kind;
+  at: string;
+  user: User | null;
+  email: string;
+  payload?: string;
+  labels: Record<string, string> | null;
+  scores: Record<string, number>;
+  "content-levels": Level[];
+  sizes: number[];
+  count?: number;
+  rate?: number;
+  parent: Event | null;
+  trace: UUID | string;
+  userId: UserID;
+  priority: Priority;
+  Extra: unknown;
+}
+
+/**
+ * Kind is the event kind.
+ *
+ * Kinds are stored as strings.
+ */
+export type Kind = "created" | "deleted";
+
+export type Level = 1 | 2;
+
+export type Priority = 1 | 2;
+
+export type UserID = string;
+`
+	if buf.String() != want {
+		t.Errorf("\nhave %s\nwant %s", buf.String(), want)
+	}
+
+	if err := g.Generate(&buf, "Missing"); err == nil {
+		t.Error("expected not found error")
+	}
+}
@@ -0,0 +1,104 @@
+package astparser
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// matchGlob reports whether slash separated name matches doublestar pattern.
+// `**` segment matches zero or more path segments, `{a,b}` matches any of
+// the alternatives, other syntax is the same as for path.Match.
+func matchGlob(pattern, name string) (bool, error) {
+	for _, p := range expandBraces(pattern) {
+		ok, err := matchSegments(strings.Split(p, "/"), strings.Split(name, "/"))
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// matchAnyGlob reports whether name matches any of the patterns.
+func matchAnyGlob(patterns []string, name string) (bool, error) {
+	for _, pattern := range patterns {
+		if ok, err := matchGlob(pattern, name); err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+func matchSegments(pattern, name []string) (bool, error) {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				ok, err := matchSegments(pattern[1:], name[i:])
+				if err != nil || ok {
+					return ok, err
+				}
+			}
+			return false, nil
+		}
+
+		if len(name) == 0 {
+			return false, nil
+		}
+		ok, err := path.Match(pattern[0], name[0])
+		if err != nil || !ok {
+			return false, err
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0, nil
+}
+
+// expandBraces expands `{a,b}` alternatives, e.g. `*.{go,tmpl}`
+// becomes `*.go` and `*.tmpl`. Nested braces are supported.
+func expandBraces(pattern string) []string {
+	start := strings.Index(pattern, "{")
+	if start < 0 {
+		return []string{pattern}
+	}
+
+	depth := 0
+	var alternatives []string
+	last := start + 1
+	for i := start; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '{':
+			depth++
+		case ',':
+			if depth == 1 {
+				alternatives = append(alternatives, pattern[last:i])
+				last = i + 1
+			}
+		case '}':
+			depth--
+			if depth > 0 {
+				continue
+			}
+			alternatives = append(alternatives, pattern[last:i])
+			var expanded []string
+			for _, alt := range alternatives {
+				expanded = append(expanded, expandBraces(pattern[:start]+alt+pattern[i+1:])...)
+			}
+			return expanded
+		}
+	}
+
+	// unbalanced brace is matched literally.
+	return []string{pattern}
+}
+
+// validateGlob reports malformed patterns.
+func validateGlob(pattern string) error {
+	for _, p := range expandBraces(pattern) {
+		for _, segment := range strings.Split(p, "/") {
+			if _, err := path.Match(segment, ""); err != nil {
+				return fmt.Errorf("invalid glob %q: %v", pattern, err)
+			}
+		}
+	}
+	return nil
+}
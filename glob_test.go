@@ -0,0 +1,48 @@
+package astparser
+
+import "testing"
+
+func Test_matchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+		wantErr bool
+	}{
+		{pattern: "event_*.go", name: "event_new.go", want: true},
+		{pattern: "event_*.go", name: "models/event_new.go", want: false},
+		{pattern: "*/event_*.go", name: "models/event_new.go", want: true},
+		{pattern: "**/event_*.go", name: "event_new.go", want: true},
+		{pattern: "**/event_*.go", name: "a/b/c/event_new.go", want: true},
+		{pattern: "models/**", name: "models/a/b.go", want: true},
+		{pattern: "models/**", name: "other/a/b.go", want: false},
+		{pattern: "a/**/b/*.go", name: "a/x/y/b/c.go", want: true},
+		{pattern: "a/**/b/*.go", name: "a/b/c.go", want: true},
+		{pattern: "a/**/b/*.go", name: "a/x/c.go", want: false},
+		{pattern: "*.{go,tmpl}", name: "event.tmpl", want: true},
+		{pattern: "{models,dto}/*.go", name: "dto/event.go", want: true},
+		{pattern: "{models,dto}/*.go", name: "api/event.go", want: false},
+		{pattern: "event_{a,{b,c}}.go", name: "event_c.go", want: true},
+		{pattern: "[", name: "event.go", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			got, err := matchGlob(tt.pattern, tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("matchGlob() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("matchGlob() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_validateGlob(t *testing.T) {
+	if err := validateGlob("**/event_{a,b}*.go"); err != nil {
+		t.Errorf("validateGlob() error = %v", err)
+	}
+	if err := validateGlob("event_[.go"); err == nil {
+		t.Error("expected error for malformed glob")
+	}
+}
@@ -2,4 +2,8 @@ module github.com/mkorolyov/astparser
 
 go 1.16
 
-require github.com/pkg/errors v0.9.1
+require (
+	github.com/BurntSushi/toml v1.2.1
+	github.com/pkg/errors v0.9.1
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,390 @@
+package astparser
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TypeNode is a named type of the model: a struct or a named type.
+type TypeNode struct {
+	// ID identifies the type across packages, like `example.com/app/models.Event`.
+	ID      string
+	Name    string
+	Package *Package
+	// Struct is set for structs, Type for other named types.
+	Struct *StructDef
+	Type   *TypeDef
+}
+
+// TypeGraph is a dependency graph of named types. A type depends on
+// the types referenced by its fields, embedded fields and underlying type,
+// so alias chains like `type A = B` make edges too.
+type TypeGraph struct {
+	nodes map[string]*TypeNode
+	ids   []string
+	// deps are sorted distinct dependencies of the loaded types,
+	// unresolved are the referenced types which are not loaded.
+	deps, dependents, unresolved map[string][]string
+}
+
+// predeclared are predeclared types parsed as custom ones.
+var predeclared = map[string]bool{
+	"any": true, "comparable": true, "complex64": true, "complex128": true,
+	"error": true, "rune": true, "uintptr": true,
+}
+
+// NewTypeGraph builds the dependency graph of the packages types.
+func NewTypeGraph(packages []*Package) *TypeGraph {
+	g := &TypeGraph{
+		nodes:      map[string]*TypeNode{},
+		deps:       map[string][]string{},
+		dependents: map[string][]string{},
+		unresolved: map[string][]string{},
+	}
+
+	type typeRef struct {
+		from string
+		file ParsedFile
+		pkg  string
+		t    Type
+	}
+	var refs []typeRef
+	for _, p := range packages {
+		pkg := packageID(p)
+		files := map[string]ParsedFile{}
+		for _, f := range p.Files {
+			files[f.Path] = f
+		}
+
+		for i := range p.Structs {
+			s := &p.Structs[i]
+			id := pkg + "." + s.Name
+			g.addNode(&TypeNode{ID: id, Name: s.Name, Package: p, Struct: s})
+			for _, field := range s.Fields {
+				refs = append(refs, typeRef{from: id, file: files[s.File], pkg: pkg, t: field.FieldType})
+			}
+		}
+		for i := range p.Types {
+			t := &p.Types[i]
+			id := pkg + "." + t.Name
+			g.addNode(&TypeNode{ID: id, Name: t.Name, Package: p, Type: t})
+			refs = append(refs, typeRef{from: id, file: files[t.File], pkg: pkg, t: t.Type})
+		}
+	}
+	sort.Strings(g.ids)
+
+	deps := map[string]map[string]bool{}
+	for _, ref := range refs {
+		for _, id := range referencedTypes(ref.file, ref.pkg, ref.t) {
+			if deps[ref.from] == nil {
+				deps[ref.from] = map[string]bool{}
+			}
+			deps[ref.from][id] = true
+		}
+	}
+	for from, ids := range deps {
+		for id := range ids {
+			if _, ok := g.nodes[id]; ok {
+				g.deps[from] = append(g.deps[from], id)
+				g.dependents[id] = append(g.dependents[id], from)
+			} else {
+				g.unresolved[from] = append(g.unresolved[from], id)
+			}
+		}
+	}
+	for _, m := range []map[string][]string{g.deps, g.dependents, g.unresolved} {
+		for _, ids := range m {
+			sort.Strings(ids)
+		}
+	}
+
+	return g
+}
+
+func (g *TypeGraph) addNode(n *TypeNode) {
+	if _, ok := g.nodes[n.ID]; !ok {
+		g.ids = append(g.ids, n.ID)
+	}
+	g.nodes[n.ID] = n
+}
+
+// packageID returns the package part of type IDs: the import path,
+// or the slash separated dir if the package has no import path.
+// In-package test files share IDs with the production package.
+func packageID(p *Package) string {
+	if p.ImportPath != "" {
+		return p.ImportPath
+	}
+	return filepath.ToSlash(p.Dir)
+}
+
+// referencedTypes returns IDs of the named types t references. Qualified types
+// are resolved by the file imports, alias chains are followed by the graph edges.
+func referencedTypes(file ParsedFile, pkg string, t Type) []string {
+	var ids []string
+	InspectType(t, func(t Type) bool {
+		c, ok := t.(TypeCustom)
+		if !ok {
+			return true
+		}
+
+		if c.Qualifier != "" || !predeclared[c.Name] {
+			ids = append(ids, customTypeID(file, pkg, c))
+		}
+		// alias type is the referenced type underlying one.
+		return false
+	})
+	return ids
+}
+
+// customTypeID returns ID of the type referenced in the file of the package pkg.
+// Predeclared types like `error` are returned as is.
+func customTypeID(file ParsedFile, pkg string, c TypeCustom) string {
+	switch {
+	case c.Qualifier != "":
+		importPath, ok := file.ImportPath(c.Qualifier)
+		if !ok {
+			importPath = c.Qualifier
+		}
+		return importPath + "." + c.Name
+	case predeclared[c.Name]:
+		return c.Name
+	default:
+		return pkg + "." + c.Name
+	}
+}
+
+// Nodes returns all the graph types sorted by ID.
+func (g *TypeGraph) Nodes() []*TypeNode {
+	nodes := make([]*TypeNode, len(g.ids))
+	for i, id := range g.ids {
+		nodes[i] = g.nodes[id]
+	}
+	return nodes
+}
+
+// Node returns the type by ID.
+func (g *TypeGraph) Node(id string) (*TypeNode, bool) {
+	n, ok := g.nodes[id]
+	return n, ok
+}
+
+// Find returns the type ID by the ID itself or by the type name if it is unique.
+func (g *TypeGraph) Find(name string) (string, error) {
+	if _, ok := g.nodes[name]; ok {
+		return name, nil
+	}
+
+	var found []string
+	for _, id := range g.ids {
+		if g.nodes[id].Name == name {
+			found = append(found, id)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("type %s not found", name)
+	case 1:
+		return found[0], nil
+	default:
+		return "", fmt.Errorf("type name %s is ambiguous: %s", name, strings.Join(found, ", "))
+	}
+}
+
+// Deps returns sorted IDs of the loaded types the type references directly.
+func (g *TypeGraph) Deps(id string) []string {
+	return g.deps[id]
+}
+
+// Dependents returns sorted IDs of the types referencing the type directly.
+func (g *TypeGraph) Dependents(id string) []string {
+	return g.dependents[id]
+}
+
+// Unresolved returns sorted IDs of the referenced types which are not loaded,
+// like `time.Time` or types of files filtered out.
+func (g *TypeGraph) Unresolved(id string) []string {
+	return g.unresolved[id]
+}
+
+// Reachable returns sorted IDs of the types transitively referenced by roots, including roots.
+// Unknown roots are ignored.
+func (g *TypeGraph) Reachable(roots ...string) []string {
+	seen := map[string]bool{}
+	var visit func(id string)
+	visit = func(id string) {
+		if seen[id] {
+			return
+		}
+		seen[id] = true
+		for _, dep := range g.deps[id] {
+			visit(dep)
+		}
+	}
+	for _, id := range roots {
+		if _, ok := g.nodes[id]; ok {
+			visit(id)
+		}
+	}
+
+	ids := make([]string, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// CycleError is returned by TopoSort if the types reference each other.
+type CycleError struct {
+	// Cycle lists the types of the cycle, each depends on the next one
+	// and the last one depends on the first.
+	Cycle []string
+}
+
+func (e *CycleError) Error() string {
+	return "types dependency cycle: " + strings.Join(e.Cycle, " -> ") + " -> " + e.Cycle[0]
+}
+
+// TopoSort returns type IDs ordered so dependencies go before the types
+// depending on them, ties are sorted by ID. Returns *CycleError if there are cycles.
+func (g *TypeGraph) TopoSort() ([]string, error) {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := map[string]int{}
+	var order, stack []string
+	var visit func(id string) error
+	visit = func(id string) error {
+		switch state[id] {
+		case done:
+			return nil
+		case visiting:
+			for i, s := range stack {
+				if s == id {
+					return &CycleError{Cycle: append([]string(nil), stack[i:]...)}
+				}
+			}
+		}
+
+		state[id] = visiting
+		stack = append(stack, id)
+		for _, dep := range g.deps[id] {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[id] = done
+		order = append(order, id)
+		return nil
+	}
+
+	for _, id := range g.ids {
+		if err := visit(id); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// Cycles returns strongly connected groups of types referencing each other,
+// including self referencing types. Types in a cycle and cycles are sorted.
+func (g *TypeGraph) Cycles() [][]string {
+	// Tarjan's strongly connected components algorithm.
+	index := map[string]int{}
+	low := map[string]int{}
+	onStack := map[string]bool{}
+	var stack []string
+	var cycles [][]string
+
+	var connect func(id string)
+	connect = func(id string) {
+		index[id] = len(index)
+		low[id] = index[id]
+		stack = append(stack, id)
+		onStack[id] = true
+
+		for _, dep := range g.deps[id] {
+			if _, ok := index[dep]; !ok {
+				connect(dep)
+				if low[dep] < low[id] {
+					low[id] = low[dep]
+				}
+			} else if onStack[dep] && index[dep] < low[id] {
+				low[id] = index[dep]
+			}
+		}
+
+		if low[id] != index[id] {
+			return
+		}
+		var component []string
+		for {
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[n] = false
+			component = append(component, n)
+			if n == id {
+				break
+			}
+		}
+		if len(component) > 1 || g.dependsOn(id, id) {
+			sort.Strings(component)
+			cycles = append(cycles, component)
+		}
+	}
+
+	for _, id := range g.ids {
+		if _, ok := index[id]; !ok {
+			connect(id)
+		}
+	}
+	sort.Slice(cycles, func(i, j int) bool { return cycles[i][0] < cycles[j][0] })
+	return cycles
+}
+
+// dependsOn reports whether from references to directly.
+func (g *TypeGraph) dependsOn(from, to string) bool {
+	deps := g.deps[from]
+	i := sort.SearchStrings(deps, to)
+	return i < len(deps) && deps[i] == to
+}
+
+// WriteDOT writes the graph in Graphviz DOT format. Structs are boxes,
+// other named types are ellipses, unresolved types are dashed.
+func (g *TypeGraph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph types {\n")
+	unresolved := map[string]bool{}
+	for _, id := range g.ids {
+		shape := "ellipse"
+		if g.nodes[id].Struct != nil {
+			shape = "box"
+		}
+		fmt.Fprintf(&b, "\t%s [shape=%s];\n", strconv.Quote(id), shape)
+		for _, u := range g.unresolved[id] {
+			unresolved[u] = true
+		}
+	}
+	for _, id := range sortedKeys(unresolved) {
+		fmt.Fprintf(&b, "\t%s [style=dashed];\n", strconv.Quote(id))
+	}
+	for _, id := range g.ids {
+		for _, dep := range g.deps[id] {
+			fmt.Fprintf(&b, "\t%s -> %s;\n", strconv.Quote(id), strconv.Quote(dep))
+		}
+		for _, dep := range g.unresolved[id] {
+			fmt.Fprintf(&b, "\t%s -> %s [style=dashed];\n", strconv.Quote(id), strconv.Quote(dep))
+		}
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,141 @@
+package astparser
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+var graphSources = map[string][]byte{
+	"go.mod": []byte("module example.com/app\n"),
+	"models/event.go": []byte(`package models
+
+import "time"
+
+type Event struct {
+	ID        EventID
+	User      *User
+	Tags      []Tag
+	CreatedAt time.Time
+	Meta      map[string]Meta
+	Err       error
+}
+
+type EventID string
+
+type Tag = Label
+
+type Label string
+
+type User struct {
+	Friends []*User
+	Address Address
+}
+
+type Address struct {
+	Owner *Owner
+}
+
+type Owner struct {
+	Address
+}
+
+type Meta struct {
+	Missing Unknown
+}
+`),
+	"dto/dto.go": []byte(`package dto
+
+import m "example.com/app/models"
+
+type Response struct {
+	Items []Item
+	Label m.Label
+}
+
+type Item struct {
+	Tag m.Tag
+}
+`),
+}
+
+func loadGraph(t *testing.T, dirs ...string) *TypeGraph {
+	packages, err := LoadPackages(Config{InputDirs: dirs, Sources: graphSources})
+	if err != nil {
+		t.Fatalf("LoadPackages() error = %v", err)
+	}
+	return NewTypeGraph(packages)
+}
+
+func TestTypeGraph(t *testing.T) {
+	g := loadGraph(t, "models", "dto")
+
+	const models = "example.com/app/models."
+	deps := g.Deps(models + "Event")
+	wantDeps := []string{models + "EventID", models + "Meta", models + "Tag", models + "User"}
+	if !reflect.DeepEqual(deps, wantDeps) {
+		t.Errorf("Deps()\nhave %+v, \nwant %+v", deps, wantDeps)
+	}
+	if got, want := g.Unresolved(models+"Event"), []string{"time.Time"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Unresolved()\nhave %+v, \nwant %+v", got, want)
+	}
+	if got, want := g.Unresolved(models+"Meta"), []string{models + "Unknown"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Unresolved()\nhave %+v, \nwant %+v", got, want)
+	}
+	if got, want := g.Deps(models+"Tag"), []string{models + "Label"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("alias Deps()\nhave %+v, \nwant %+v", got, want)
+	}
+	if got, want := g.Dependents(models+"Label"), []string{"example.com/app/dto.Response", models + "Tag"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Dependents()\nhave %+v, \nwant %+v", got, want)
+	}
+
+	reachable := g.Reachable("example.com/app/dto.Response")
+	wantReachable := []string{"example.com/app/dto.Item", "example.com/app/dto.Response", models + "Label", models + "Tag"}
+	if !reflect.DeepEqual(reachable, wantReachable) {
+		t.Errorf("Reachable()\nhave %+v, \nwant %+v", reachable, wantReachable)
+	}
+
+	cycles := g.Cycles()
+	wantCycles := [][]string{{models + "Address", models + "Owner"}, {models + "User"}}
+	if !reflect.DeepEqual(cycles, wantCycles) {
+		t.Errorf("Cycles()\nhave %+v, \nwant %+v", cycles, wantCycles)
+	}
+
+	_, err := g.TopoSort()
+	if cycleErr, ok := err.(*CycleError); !ok || len(cycleErr.Cycle) == 0 {
+		t.Errorf("TopoSort() error = %v, want CycleError", err)
+	}
+}
+
+func TestTypeGraph_TopoSort(t *testing.T) {
+	g := loadGraph(t, "dto")
+	order, err := g.TopoSort()
+	if err != nil {
+		t.Fatalf("TopoSort() error = %v", err)
+	}
+	want := []string{"example.com/app/dto.Item", "example.com/app/dto.Response"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("\nhave %+v, \nwant %+v", order, want)
+	}
+}
+
+func TestTypeGraph_WriteDOT(t *testing.T) {
+	var buf bytes.Buffer
+	if err := loadGraph(t, "dto").WriteDOT(&buf); err != nil {
+		t.Fatalf("WriteDOT() error = %v", err)
+	}
+
+	want := `digraph types {
+	"example.com/app/dto.Item" [shape=box];
+	"example.com/app/dto.Response" [shape=box];
+	"example.com/app/models.Label" [style=dashed];
+	"example.com/app/models.Tag" [style=dashed];
+	"example.com/app/dto.Item" -> "example.com/app/models.Tag" [style=dashed];
+	"example.com/app/dto.Response" -> "example.com/app/dto.Item";
+	"example.com/app/dto.Response" -> "example.com/app/models.Label" [style=dashed];
+}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("\nhave %s\nwant %s", got, want)
+	}
+}
@@ -0,0 +1,206 @@
+package astparser
+
+import (
+	"context"
+	"go/token"
+	"io/fs"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Loader keeps parsed files between loads, so after the first load
+// only changed files are parsed again. It is safe for concurrent use.
+// token.FileSet keeps every file added to it, so each load parses files
+// into a new one to keep memory of long running loaders bounded and
+// Config.Fset must be nil. Positions of parsed files are resolved at parse time.
+type Loader struct {
+	cfg      Config
+	sources  []source
+	filters  []*fileFilter
+	resolver *importPathResolver
+
+	mu       sync.Mutex
+	parsed   map[string]ParsedFile
+	packages []*Package
+
+	// ticks drive Watch polls instead of a ticker if set, tests use it.
+	ticks <-chan time.Time
+}
+
+// NewLoader validates config and creates a Loader.
+func NewLoader(cfg Config) (*Loader, error) {
+	if cfg.Fset != nil {
+		return nil, errors.New("invalid config: Loader doesn't support Fset, it parses each load into a new file set")
+	}
+	if err := cfg.prepare(); err != nil {
+		return nil, errors.Wrap(err, "invalid config")
+	}
+
+	l := &Loader{cfg: cfg, sources: newSources(cfg)}
+	for _, src := range l.sources {
+		filter, err := newFileFilter(cfg, src)
+		if err != nil {
+			return nil, err
+		}
+		l.filters = append(l.filters, filter)
+	}
+	l.resolver = newImportPathResolver(l.sources)
+	return l, nil
+}
+
+// Packages returns packages of the last successful load.
+func (l *Loader) Packages() []*Package {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.packages
+}
+
+// Load parses all the input dir files like LoadPackages does.
+func (l *Loader) Load(ctx context.Context) ([]*Package, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.load(ctx); err != nil {
+		return nil, err
+	}
+	return l.packages, nil
+}
+
+func (l *Loader) load(ctx context.Context) error {
+	refs, err := listFiles(l.cfg, l.sources)
+	if err != nil {
+		return err
+	}
+
+	parsed := make(map[string]ParsedFile, len(refs))
+	if err := l.parse(ctx, parsed, refs); err != nil {
+		return err
+	}
+
+	return l.setParsed(parsed)
+}
+
+// setParsed replaces parsed files and packages made of them.
+func (l *Loader) setParsed(parsed map[string]ParsedFile) error {
+	packages, err := newPackages(l.resolver, selectFiles(l.cfg, parsed))
+	if err != nil {
+		return err
+	}
+	l.parsed = parsed
+	l.packages = packages
+	return nil
+}
+
+// Update parses changed files again, forgets deleted ones and returns
+// updated packages with the changes made since the previous load.
+// Paths are either absolute or in the ParsedFile.Path form, paths
+// outside of the input dirs are ignored. Loads all the files if
+// it is the first load.
+func (l *Loader) Update(ctx context.Context, changed, deleted []string) ([]*Package, ChangeSet, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	changes, _, _, err := l.update(ctx, changed, deleted)
+	if err != nil {
+		return nil, ChangeSet{}, err
+	}
+	return l.packages, changes, nil
+}
+
+// update is Update which also returns parsed again files and paths of the removed ones.
+func (l *Loader) update(ctx context.Context, changed, deleted []string) (ChangeSet, []ParsedFile, []string, error) {
+	old := l.packages
+	if l.parsed == nil {
+		if err := l.load(ctx); err != nil {
+			return ChangeSet{}, nil, nil, err
+		}
+		return diffPackages(old, l.packages), nil, nil, nil
+	}
+
+	parsed := make(map[string]ParsedFile, len(l.parsed))
+	for name, f := range l.parsed {
+		parsed[name] = f
+	}
+
+	for _, p := range deleted {
+		if ref, _, ok := l.fileRef(p); ok {
+			delete(parsed, ref.path())
+		}
+	}
+
+	seen := map[string]bool{}
+	var toParse []fileRef
+	for _, p := range changed {
+		ref, filter, ok := l.fileRef(p)
+		if !ok || seen[ref.path()] {
+			continue
+		}
+		seen[ref.path()] = true
+
+		// file could be removed right after it was reported as changed.
+		if _, err := fs.Stat(ref.src.fsys, ref.src.path(ref.name)); os.IsNotExist(err) {
+			delete(parsed, ref.path())
+			continue
+		}
+
+		match, err := filter.match(ref.name)
+		if err != nil {
+			return ChangeSet{}, nil, nil, err
+		}
+		if !match {
+			delete(parsed, ref.path())
+			continue
+		}
+		toParse = append(toParse, ref)
+	}
+
+	if err := l.parse(ctx, parsed, toParse); err != nil {
+		return ChangeSet{}, nil, nil, err
+	}
+
+	var reparsed []ParsedFile
+	for _, ref := range toParse {
+		reparsed = append(reparsed, parsed[ref.path()])
+	}
+	var removed []string
+	for name, f := range l.parsed {
+		if _, ok := parsed[name]; !ok {
+			removed = append(removed, f.Path)
+		}
+	}
+	sort.Strings(removed)
+
+	if err := l.setParsed(parsed); err != nil {
+		return ChangeSet{}, nil, nil, err
+	}
+	return diffPackages(old, l.packages), reparsed, removed, nil
+}
+
+// parse parses files into parsed by ParsedFile.Path using a new file set.
+func (l *Loader) parse(ctx context.Context, parsed map[string]ParsedFile, refs []fileRef) error {
+	cfg := l.cfg
+	cfg.Fset = token.NewFileSet()
+	files, err := parseFiles(ctx, cfg, refs)
+	if err != nil {
+		return err
+	}
+	for i, f := range files {
+		parsed[refs[i].path()] = f
+	}
+	return nil
+}
+
+// fileRef returns the input dir file of the path and its filter,
+// false if the path is outside of all the input dirs.
+func (l *Loader) fileRef(p string) (fileRef, *fileFilter, bool) {
+	for i, src := range l.sources {
+		if name, ok := src.relName(p); ok {
+			return fileRef{src: src, name: name}, l.filters[i], true
+		}
+	}
+	return fileRef{}, nil, false
+}
@@ -0,0 +1,107 @@
+package astparser
+
+import (
+	"context"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoader_Update(t *testing.T) {
+	dir, err := ioutil.TempDir("", "astparser-loader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) string {
+		p := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	event := write("event.go", `package models
+
+const Version = "1"
+
+type Event struct {
+	Name string `+"`json:\"name\"`"+`
+	Dep
+}
+
+type Dep struct{}
+`)
+	legacy := write("legacy.go", "package models\n\ntype Legacy struct{}\n")
+
+	l, err := NewLoader(Config{InputDir: dir})
+	if err != nil {
+		t.Fatalf("NewLoader() error = %v", err)
+	}
+	if _, err := l.Load(context.Background()); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	write("event.go", `package models
+
+const Version = "2"
+
+type Event struct {
+	Name string `+"`json:\"title\"`"+`
+	ID   int
+	*Dep
+}
+
+type Dep struct{}
+`)
+	user := write("user.go", "package models\n\ntype User struct{}\n")
+	if err := os.Remove(legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	packages, changes, err := l.Update(context.Background(), []string{event, user, filepath.Join(dir, "..", "outside.go")}, []string{legacy})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	pkg := packages[0].Name
+	want := ChangeSet{
+		Structs: []Change{
+			{Kind: ChangeModified, Package: pkg, Name: "Event", File: event},
+			{Kind: ChangeRemoved, Package: pkg, Name: "Legacy", File: legacy},
+			{Kind: ChangeAdded, Package: pkg, Name: "User", File: user},
+		},
+		Constants: []Change{
+			{Kind: ChangeModified, Package: pkg, Name: "Version", File: event},
+		},
+		Fields: []Change{
+			{Kind: ChangeAdded, Package: pkg, Struct: "Event", Name: "*Dep", File: event},
+			{Kind: ChangeRemoved, Package: pkg, Struct: "Event", Name: "Dep", File: event},
+			{Kind: ChangeAdded, Package: pkg, Struct: "Event", Name: "ID", File: event},
+			{Kind: ChangeModified, Package: pkg, Struct: "Event", Name: "Name", File: event},
+		},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("\nhave %+v, \nwant %+v", changes, want)
+	}
+
+	if !reflect.DeepEqual(l.Packages(), packages) {
+		t.Errorf("Packages() must return the updated packages")
+	}
+
+	_, changes, err = l.Update(context.Background(), []string{event}, nil)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if !changes.Empty() {
+		t.Errorf("expected no changes for unchanged file, got %+v", changes)
+	}
+
+	if _, err := NewLoader(Config{InputDir: dir, Fset: token.NewFileSet()}); err == nil {
+		t.Error("expected Fset error")
+	}
+}
@@ -0,0 +1,266 @@
+package astparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+)
+
+// ModelVersion is the version of the JSON model encoding.
+// It is increased on incompatible changes of the format.
+const ModelVersion = 1
+
+// The model is encoded to JSON as
+//
+//	{"version": 1, "packages": [{"name": "models", "import_path": "...", "files": [...]}]}
+//
+// Declarations use snake_case keys of their Go field names, e.g. `field_name`.
+// Every Type is an object with a `kind` discriminator:
+//
+//	{"kind": "simple", "name": "string"}
+//	{"kind": "array", "inner": <type>}
+//	{"kind": "map", "key": <type>, "value": <type>}
+//	{"kind": "custom", "name": "Time", "qualifier": "time", "alias": false, "alias_type": <type>}
+//	{"kind": "pointer", "inner": <type>}
+//	{"kind": "interface"}
+//
+// Kind is the Type.Kind name. Nil types are encoded as null. TypeCustom.Expr
+// is not encoded and is restored for qualified types only, like the parse cache does.
+type jsonModel struct {
+	Version  int        `json:"version"`
+	Packages []*Package `json:"packages"`
+}
+
+// MarshalModel encodes packages to the versioned JSON model.
+func MarshalModel(packages []*Package) ([]byte, error) {
+	return json.Marshal(jsonModel{Version: ModelVersion, Packages: packages})
+}
+
+// UnmarshalModel decodes packages encoded by MarshalModel.
+// Package declarations and lookup index are restored from the files.
+func UnmarshalModel(data []byte) ([]*Package, error) {
+	var model jsonModel
+	if err := json.Unmarshal(data, &model); err != nil {
+		return nil, err
+	}
+	if model.Version != ModelVersion {
+		return nil, fmt.Errorf("unsupported model version %d, want %d", model.Version, ModelVersion)
+	}
+	return model.Packages, nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (p *Package) UnmarshalJSON(data []byte) error {
+	type plain Package
+	var v plain
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*p = Package{Name: v.Name, ImportPath: v.ImportPath, Dir: v.Dir, Test: v.Test, Doc: v.Doc}
+	for _, f := range v.Files {
+		p.addFile(f)
+	}
+	p.buildIndex()
+	return nil
+}
+
+type jsonPosition struct {
+	Filename string `json:"filename"`
+	Offset   int    `json:"offset"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+}
+
+type jsonImport struct {
+	Name     string       `json:"name"`
+	Path     string       `json:"path"`
+	Comments []string     `json:"comments"`
+	Pos      jsonPosition `json:"pos"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (i ImportDef) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonImport{
+		Name:     i.Name,
+		Path:     i.Path,
+		Comments: i.Comments,
+		Pos:      jsonPosition(i.Pos),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (i *ImportDef) UnmarshalJSON(data []byte) error {
+	var v jsonImport
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*i = ImportDef{Name: v.Name, Path: v.Path, Comments: v.Comments, Pos: token.Position(v.Pos)}
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *TypeDef) UnmarshalJSON(data []byte) error {
+	type plain TypeDef
+	var v struct {
+		plain
+		Type json.RawMessage `json:"type"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	t, err := unmarshalType(v.Type)
+	if err != nil {
+		return fmt.Errorf("type %s: %v", v.Name, err)
+	}
+	*d = TypeDef(v.plain)
+	d.Type = t
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (f *FieldDef) UnmarshalJSON(data []byte) error {
+	type plain FieldDef
+	var v struct {
+		plain
+		FieldType json.RawMessage `json:"field_type"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	t, err := unmarshalType(v.FieldType)
+	if err != nil {
+		return fmt.Errorf("field %s: %v", v.FieldName, err)
+	}
+	*f = FieldDef(v.plain)
+	f.FieldType = t
+	return nil
+}
+
+// jsonType is a Type of any kind.
+type jsonType struct {
+	Kind      string          `json:"kind"`
+	Name      string          `json:"name,omitempty"`
+	Qualifier string          `json:"qualifier,omitempty"`
+	Alias     bool            `json:"alias,omitempty"`
+	AliasType json.RawMessage `json:"alias_type,omitempty"`
+	Inner     json.RawMessage `json:"inner,omitempty"`
+	Key       json.RawMessage `json:"key,omitempty"`
+	Value     json.RawMessage `json:"value,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t TypeSimple) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonType{Kind: t.Kind().String(), Name: t.Name})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t TypeArray) MarshalJSON() ([]byte, error) {
+	inner, err := json.Marshal(t.InnerType)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonType{Kind: t.Kind().String(), Inner: inner})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t TypeMap) MarshalJSON() ([]byte, error) {
+	key, err := json.Marshal(t.KeyType)
+	if err != nil {
+		return nil, err
+	}
+	value, err := json.Marshal(t.ValueType)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonType{Kind: t.Kind().String(), Key: key, Value: value})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t TypeCustom) MarshalJSON() ([]byte, error) {
+	v := jsonType{Kind: t.Kind().String(), Name: t.Name, Qualifier: t.Qualifier, Alias: t.Alias}
+	if t.AliasType != nil {
+		aliasType, err := json.Marshal(t.AliasType)
+		if err != nil {
+			return nil, err
+		}
+		v.AliasType = aliasType
+	}
+	return json.Marshal(v)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t TypePointer) MarshalJSON() ([]byte, error) {
+	inner, err := json.Marshal(t.InnerType)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonType{Kind: t.Kind().String(), Inner: inner})
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t TypeInterfaceValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonType{Kind: t.Kind().String()})
+}
+
+// unmarshalType decodes a Type of any kind, null gives nil.
+func unmarshalType(data json.RawMessage) (Type, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return nil, nil
+	}
+
+	var v jsonType
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+
+	kind, ok := parseKind(v.Kind)
+	if !ok {
+		return nil, fmt.Errorf("unknown type kind %q", v.Kind)
+	}
+
+	switch kind {
+	case KindSimple:
+		return TypeSimple{Name: v.Name}, nil
+	case KindArray:
+		inner, err := unmarshalType(v.Inner)
+		if err != nil {
+			return nil, err
+		}
+		return TypeArray{InnerType: inner}, nil
+	case KindMap:
+		key, err := unmarshalType(v.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := unmarshalType(v.Value)
+		if err != nil {
+			return nil, err
+		}
+		return TypeMap{KeyType: key, ValueType: value}, nil
+	case KindCustom:
+		aliasType, err := unmarshalType(v.AliasType)
+		if err != nil {
+			return nil, err
+		}
+		return TypeCustom{
+			Alias:     v.Alias,
+			AliasType: aliasType,
+			Name:      v.Name,
+			Qualifier: v.Qualifier,
+			Expr:      qualifiedExpr(v.Qualifier, v.Name),
+		}, nil
+	case KindPointer:
+		inner, err := unmarshalType(v.Inner)
+		if err != nil {
+			return nil, err
+		}
+		return TypePointer{InnerType: inner}, nil
+	case KindInterface:
+		return TypeInterfaceValue{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported type kind %q", v.Kind)
+	}
+}
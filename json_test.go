@@ -0,0 +1,110 @@
+package astparser
+
+import (
+	"encoding/json"
+	"go/ast"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMarshalModel(t *testing.T) {
+	packages, err := LoadPackages(Config{InputDir: "fixtures_test", IncludeTests: true, IncludeExternalTests: true})
+	if err != nil {
+		t.Fatalf("LoadPackages() error = %v", err)
+	}
+
+	data, err := MarshalModel(packages)
+	if err != nil {
+		t.Fatalf("MarshalModel() error = %v", err)
+	}
+	decoded, err := UnmarshalModel(data)
+	if err != nil {
+		t.Fatalf("UnmarshalModel() error = %v", err)
+	}
+
+	again, err := MarshalModel(decoded)
+	if err != nil {
+		t.Fatalf("MarshalModel() error = %v", err)
+	}
+	if string(again) != string(data) {
+		t.Errorf("model changed after round trip\nhave %s\nwant %s", again, data)
+	}
+
+	if len(decoded) != len(packages) {
+		t.Fatalf("expected %d packages, got %d", len(packages), len(decoded))
+	}
+	for i, p := range decoded {
+		if len(p.Structs) != len(packages[i].Structs) || !reflect.DeepEqual(p.Imports, packages[i].Imports) {
+			t.Errorf("package %s declarations are not restored", p.Name)
+		}
+	}
+
+	d, ok := decoded[0].Lookup("Event")
+	if !ok {
+		t.Fatal("Event is not found in decoded package")
+	}
+	want := TypeCustom{
+		Name:      "Time",
+		Qualifier: "time",
+		Expr:      &ast.SelectorExpr{X: ast.NewIdent("time"), Sel: ast.NewIdent("Time")},
+	}
+	if got := d.Struct.Fields[1].FieldType; !reflect.DeepEqual(got, want) {
+		t.Errorf("\nhave %+v, \nwant %+v", got, want)
+	}
+}
+
+func TestMarshalModel_types(t *testing.T) {
+	field := FieldDef{
+		FieldName: "Deps",
+		FieldType: TypeMap{
+			KeyType: TypeSimple{Name: "string"},
+			ValueType: TypeArray{InnerType: TypePointer{InnerType: TypeCustom{
+				Name:      "Dep",
+				AliasType: TypeInterfaceValue{},
+			}}},
+		},
+	}
+
+	data, err := json.Marshal(field)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	wantJSON := `"field_type":{"kind":"map",` +
+		`"key":{"kind":"simple","name":"string"},` +
+		`"value":{"kind":"array","inner":{"kind":"pointer","inner":` +
+		`{"kind":"custom","name":"Dep","alias_type":{"kind":"interface"}}}}}`
+	if !strings.Contains(string(data), wantJSON) {
+		t.Errorf("unexpected encoding %s", data)
+	}
+
+	var got FieldDef
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, field) {
+		t.Errorf("\nhave %+v, \nwant %+v", got, field)
+	}
+}
+
+func TestUnmarshalModel_errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{name: "version", data: `{"version": 2}`, wantErr: "unsupported model version 2"},
+		{
+			name:    "kind",
+			data:    `{"version": 1, "packages": [{"files": [{"types": [{"name": "A", "type": {"kind": "chan"}}]}]}]}`,
+			wantErr: `type A: unknown type kind "chan"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := UnmarshalModel([]byte(tt.data)); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("UnmarshalModel() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package astparser
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// JSONField is a struct field as encoding/json sees it.
+type JSONField struct {
+	// Name is the JSON object key: JsonName or the Go field name.
+	Name  string
+	Field *FieldDef
+	// Struct declares the field, it is an embedded struct for promoted fields.
+	// Field types are resolved against it, see Model.TypeID.
+	Struct *TypeNode
+	// Omitempty is set by the `omitempty` json tag option.
+	Omitempty bool
+	// Nullable is set by the `nullable:"true"` tag.
+	Nullable bool
+}
+
+// JSONFields returns the struct fields in the order encoding/json encodes them.
+// Unexported fields are skipped, fields of embedded structs without json name
+// are promoted. Fields of the same name follow encoding/json dominance rule:
+// the shallowest one wins, the tagged one if there are several of the same depth,
+// and the name is dropped if it is still ambiguous. Embedded types which are
+// not loaded, like time.Time, are skipped since their fields are unknown.
+func (m *Model) JSONFields(n *TypeNode) []JSONField {
+	type candidate struct {
+		field  JSONField
+		depth  int
+		tagged bool
+	}
+	var candidates []candidate
+	depths := map[string]int{}
+
+	var collect func(n *TypeNode, depth int, seen map[string]bool)
+	collect = func(n *TypeNode, depth int, seen map[string]bool) {
+		if n.Struct == nil || seen[n.ID] {
+			return
+		}
+		seen[n.ID] = true
+		defer delete(seen, n.ID)
+
+		for i := range n.Struct.Fields {
+			f := &n.Struct.Fields[i]
+			name := f.JsonName
+			if f.CompositionField && name == "" {
+				embedded, typeName := m.embeddedType(n, f.FieldType)
+				if embedded == nil {
+					continue
+				}
+				if embedded.Struct != nil {
+					collect(embedded, depth+1, seen)
+					continue
+				}
+				if !isExported(typeName) {
+					continue
+				}
+				name = typeName
+			}
+			if !f.CompositionField && !isExported(f.FieldName) {
+				continue
+			}
+			if name == "" {
+				name = f.FieldName
+			}
+
+			candidates = append(candidates, candidate{depth: depth, tagged: f.JsonName != "", field: JSONField{
+				Name:      name,
+				Field:     f,
+				Struct:    n,
+				Omitempty: hasJSONOption(f.AllTags["json"], "omitempty"),
+				Nullable:  f.AllTags["nullable"] == "true",
+			}})
+			if d, ok := depths[name]; !ok || depth < d {
+				depths[name] = depth
+			}
+		}
+	}
+	collect(n, 0, map[string]bool{})
+
+	// count the shallowest fields of each name and the tagged ones of them.
+	type group struct{ fields, tagged int }
+	groups := map[string]*group{}
+	for _, c := range candidates {
+		if c.depth != depths[c.field.Name] {
+			continue
+		}
+		g, ok := groups[c.field.Name]
+		if !ok {
+			g = &group{}
+			groups[c.field.Name] = g
+		}
+		g.fields++
+		if c.tagged {
+			g.tagged++
+		}
+	}
+
+	var fields []JSONField
+	for _, c := range candidates {
+		g := groups[c.field.Name]
+		if c.depth == depths[c.field.Name] && (g.fields == 1 || g.tagged == 1 && c.tagged) {
+			fields = append(fields, c.field)
+		}
+	}
+	return fields
+}
+
+// embeddedType returns the embedded field type if it is loaded and its name.
+func (m *Model) embeddedType(n *TypeNode, t Type) (*TypeNode, string) {
+	if p, ok := t.(TypePointer); ok {
+		t = p.InnerType
+	}
+	c, ok := t.(TypeCustom)
+	if !ok {
+		return nil, ""
+	}
+	embedded, _ := m.graph.Node(m.TypeID(n, c))
+	return embedded, c.Name
+}
+
+func hasJSONOption(tag, option string) bool {
+	parts := strings.Split(tag, ",")
+	for _, o := range parts[1:] {
+		if strings.TrimSpace(o) == option {
+			return true
+		}
+	}
+	return false
+}
+
+func isExported(name string) bool {
+	r, _ := utf8.DecodeRuneInString(name)
+	return unicode.IsUpper(r)
+}
@@ -1,104 +1,353 @@
 package astparser
 
 import (
+	"context"
 	"fmt"
 	"go/ast"
+	"go/build"
 	"go/parser"
 	"go/token"
-	"io/ioutil"
+	"io"
+	"io/fs"
+	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 )
 
 // Load parses files and return a map where key is a file name and
-// value as a parsed file obj with golang structs definitions and constants
+// value as a parsed file obj with golang structs definitions and constants.
+// File names are relative to the input dir. Load supports one input dir,
+// use LoadPackages to load several.
 func Load(cfg Config) (map[string]ParsedFile, error) {
+	return LoadContext(context.Background(), cfg)
+}
+
+// LoadContext is like Load but stops parsing once ctx is done.
+func LoadContext(ctx context.Context, cfg Config) (map[string]ParsedFile, error) {
 	if err := cfg.prepare(); err != nil {
-		return nil, errors.Wrapf(err, "unexpected config %+v", cfg)
+		return nil, errors.Wrap(err, "invalid config")
+	}
+	if len(cfg.inputDirs()) > 1 {
+		return nil, errors.New("invalid config: Load supports one input dir, use LoadPackages to load several")
+	}
+	return loadFiles(ctx, cfg, false)
+}
+
+// loadFiles loads files of the prepared config keyed by file names relative
+// to the input dir, or by ParsedFile.Path if byPath is set.
+func loadFiles(ctx context.Context, cfg Config, byPath bool) (map[string]ParsedFile, error) {
+	sources := newSources(cfg)
+	refs, err := listFiles(cfg, sources)
+	if err != nil {
+		return nil, err
 	}
 
-	fileNames, err := getFilesNames(cfg)
+	files, err := parseFiles(ctx, cfg, refs)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to read files from input dir %s", cfg.InputDir)
+		return nil, err
+	}
+
+	parsed := make(map[string]ParsedFile, len(files))
+	for i, file := range files {
+		name := refs[i].name
+		if byPath {
+			name = file.Path
+		}
+		parsed[name] = file
 	}
 
-	result := map[string]ParsedFile{}
-	for _, f := range fileNames {
-		filePath := filepath.Join(cfg.InputDir, f)
-		file, err := parseFile(filePath)
+	return selectFiles(cfg, parsed), nil
+}
+
+// selectFiles returns parsed files without generated and unwanted test ones if configured.
+func selectFiles(cfg Config, parsed map[string]ParsedFile) map[string]ParsedFile {
+	result := make(map[string]ParsedFile, len(parsed))
+	for name, file := range parsed {
+		if cfg.SkipGenerated && file.IsGenerated {
+			continue
+		}
+		result[name] = file
+	}
+
+	if cfg.includeAnyTests() {
+		filterTests(cfg, result)
+	}
+
+	return result
+}
+
+// parseFiles parses files with up to cfg.Concurrency workers
+// and returns them in the refs order.
+func parseFiles(ctx context.Context, cfg Config, refs []fileRef) ([]ParsedFile, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	cache := newFileCache(cfg)
+	observe := newObserver(cfg.Observer, len(refs))
+	files := make([]ParsedFile, len(refs))
+	errs := make([]error, len(refs))
+	jobs := make(chan int)
+
+	workers := cfg.Concurrency
+	if workers > len(refs) {
+		workers = len(refs)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				filePath := refs[i].path()
+				observe(FileEvent{Kind: FileStarted, Path: filePath})
+
+				start := time.Now()
+				var cached bool
+				files[i], cached, errs[i] = parseSourceFile(cfg.Fset, cache, refs[i], cfg.SkipGenerated)
+				event := FileEvent{Kind: FileFinished, Path: filePath, Duration: time.Since(start), Cached: cached}
+				if errs[i] != nil {
+					event.Kind, event.Err = FileFailed, errs[i]
+					cancel()
+				}
+				observe(event)
+			}
+		}()
+	}
+
+	var ctxErr error
+loop:
+	for i := range refs {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			ctxErr = ctx.Err()
+			break loop
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	// report the first parse error in files order, it is the reason of cancellation.
+	for _, err := range errs {
 		if err != nil {
-			return nil, errors.Wrapf(err, "failed to parse file %s", filePath)
+			return nil, err
 		}
-		result[f] = ParsedFile{
-			Structs:   file.Structs,
-			Constants: file.Constants,
-			Package:   file.Package}
+	}
+	if ctxErr != nil {
+		return nil, ctxErr
 	}
 
-	return result, nil
+	return files, nil
+}
+
+// parseSourceFile parses the input dir file and reports whether it was found in the cache.
+// Cache is optional. If skipGenerated is set, generated files are not parsed beyond
+// the package clause, only their Path, Package and IsGenerated are set.
+func parseSourceFile(fileSet *token.FileSet, cache *fileCache, ref fileRef, skipGenerated bool) (ParsedFile, bool, error) {
+	filePath := ref.path()
+	data, err := ref.src.readFile(ref.name)
+	if err != nil {
+		return ParsedFile{}, false, errors.Wrapf(err, "failed to read file %s", filePath)
+	}
+
+	if skipGenerated {
+		// parse errors are reported by the full parse below.
+		header, err := parser.ParseFile(token.NewFileSet(), filePath, data, parser.PackageClauseOnly|parser.ParseComments)
+		if err == nil && isGenerated(header) {
+			return ParsedFile{Path: filePath, Package: header.Name.Name, IsGenerated: true}, false, nil
+		}
+	}
+
+	if cache != nil {
+		if file, ok := cache.get(filePath, data); ok {
+			return file, true, nil
+		}
+	}
+
+	file, err := parseSource(fileSet, filePath, data)
+	if err != nil {
+		return ParsedFile{}, false, errors.Wrapf(err, "failed to parse file %s", filePath)
+	}
+
+	if cache != nil {
+		cache.put(filePath, data, file)
+	}
+	return file, false, nil
+}
+
+// filterTests marks test files and drops the ones of unwanted test package kind.
+func filterTests(cfg Config, files map[string]ParsedFile) {
+	// production package names by dir.
+	prodPackages := map[string]string{}
+	for name, f := range files {
+		if !isTestFile(name) {
+			prodPackages[filepath.Dir(f.Path)] = f.Package
+		}
+	}
+
+	for name, f := range files {
+		if !isTestFile(name) {
+			continue
+		}
+		external := isExternalTest(f.Package, prodPackages[filepath.Dir(f.Path)])
+		if external && !cfg.IncludeExternalTests || !external && !cfg.IncludeTests {
+			delete(files, name)
+			continue
+		}
+		f.IsTest = true
+		files[name] = f
+	}
 }
 
 func parseFile(file string) (ParsedFile, error) {
-	fileSet := token.NewFileSet()
-	parsedFile, err := parser.ParseFile(fileSet, file, nil, parser.ParseComments)
+	return parseSource(token.NewFileSet(), file, nil)
+}
+
+// parseSource parses file content, reads it from disk if src is nil.
+func parseSource(fileSet *token.FileSet, file string, src []byte) (ParsedFile, error) {
+	var content interface{}
+	if src != nil {
+		content = src
+	}
+	parsedFile, err := parser.ParseFile(fileSet, file, content, parser.ParseComments)
 	if err != nil {
 		return ParsedFile{}, errors.Wrapf(err, "cant parse file: %s", file)
 	}
-	walker := &Walker{}
+	walker := &Walker{file: file, fset: fileSet}
 	ast.Walk(walker, parsedFile)
+	if walker.Err != nil {
+		return ParsedFile{}, walker.Err
+	}
 	return ParsedFile{
-		Structs:   walker.Structs,
-		Constants: walker.Constants,
-		Package:   walker.Package,
+		Path:            file,
+		Structs:         walker.Structs,
+		Types:           walker.Types,
+		Constants:       walker.Constants,
+		Funcs:           walker.Funcs,
+		Imports:         walker.Imports,
+		BuildConstraint: walker.BuildConstraint,
+		IsGenerated:     walker.IsGenerated,
+		Package:         walker.Package,
+		PackageDoc:      walker.PackageDoc,
 	}, nil
 }
 
-func getFilesNames(cfg Config) ([]string, error) {
-	var fileNames []string
-	files, err := ioutil.ReadDir(cfg.InputDir)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read input dir %s: %s", cfg.InputDir, err)
+// fileRef is a file of one of the input dirs.
+type fileRef struct {
+	src source
+	// name is a slash separated path relative to the input dir.
+	name string
+}
+
+func (r fileRef) path() string {
+	return r.src.displayPath(r.name)
+}
+
+// listFiles returns files of all the sources to be loaded.
+// Files found in several overlapping input dirs are listed once.
+func listFiles(cfg Config, sources []source) ([]fileRef, error) {
+	seen := map[string]bool{}
+	var refs []fileRef
+	for _, src := range sources {
+		filter, err := newFileFilter(cfg, src)
+		if err != nil {
+			return nil, err
+		}
+
+		err = src.walk(func(name string, d fs.DirEntry) error {
+			match, err := filter.match(name)
+			if err != nil || !match {
+				return err
+			}
+			ref := fileRef{src: src, name: name}
+			if !seen[ref.path()] {
+				seen[ref.path()] = true
+				refs = append(refs, ref)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read input dir %s: %s", src.displayPath("."), err)
+		}
 	}
+	return refs, nil
+}
+
+// fileFilter decides which input dir files should be loaded.
+type fileFilter struct {
+	tests                        bool
+	includeRegexp, excludeRegexp *regexp.Regexp
+	patterns, excludePatterns    []string
+	buildCtx                     *build.Context
+	root                         string
+}
 
-	var includeRegexp, excludeRegexp *regexp.Regexp
+func newFileFilter(cfg Config, src source) (*fileFilter, error) {
+	f := &fileFilter{
+		tests:           cfg.includeAnyTests(),
+		patterns:        cfg.Patterns,
+		excludePatterns: cfg.ExcludePatterns,
+		root:            src.root,
+	}
+
+	var err error
 	if cfg.ExcludeRegexp != "" {
-		excludeRegexp, err = regexp.Compile(cfg.ExcludeRegexp)
+		f.excludeRegexp, err = regexp.Compile(cfg.ExcludeRegexp)
 		if err != nil {
 			return nil, fmt.Errorf("failed to compile exclude regexp %q: %v", cfg.ExcludeRegexp, err)
 		}
 	}
 
 	if cfg.IncludeRegexp != "" {
-		includeRegexp, err = regexp.Compile(cfg.IncludeRegexp)
+		f.includeRegexp, err = regexp.Compile(cfg.IncludeRegexp)
 		if err != nil {
-			return nil, fmt.Errorf("failed to compile exclude regexp %q: %v", cfg.IncludeRegexp, err)
+			return nil, fmt.Errorf("failed to compile include regexp %q: %v", cfg.IncludeRegexp, err)
 		}
 	}
 
-	for _, f := range files {
-		// skip if file is dir, is test, is not a go file, matches exclude regexp or don't matches include one.
-		if f.IsDir() || !validFile(f.Name(), includeRegexp, excludeRegexp) {
-			continue
-		}
+	f.buildCtx = cfg.buildContext()
+	f.buildCtx.JoinPath = path.Join
+	f.buildCtx.OpenFile = func(name string) (io.ReadCloser, error) { return src.fsys.Open(name) }
+	return f, nil
+}
 
-		fileNames = append(fileNames, f.Name())
+// match reports whether the input dir file should be loaded.
+// Name is a slash separated path relative to the input dir.
+func (f *fileFilter) match(name string) (bool, error) {
+	// skip if file is test, is not a go file, matches exclude regexp or don't matches include one.
+	if !validFile(name, f.tests, f.includeRegexp, f.excludeRegexp) {
+		return false, nil
 	}
-	return fileNames, nil
+
+	// skip if file don't matches any of include patterns or matches an exclude one.
+	if match, err := matchAnyGlob(f.patterns, name); err != nil || len(f.patterns) > 0 && !match {
+		return false, err
+	}
+	if match, err := matchAnyGlob(f.excludePatterns, name); err != nil || match {
+		return false, err
+	}
+
+	// skip files excluded by build constraints or GOOS/GOARCH file name suffix.
+	match, err := f.buildCtx.MatchFile(path.Join(f.root, path.Dir(name)), path.Base(name))
+	if err != nil {
+		return false, fmt.Errorf("failed to match build constraints of %s: %v", name, err)
+	}
+	return match, nil
 }
 
-func validFile(name string, include, exclude *regexp.Regexp) bool {
-	if include != nil {
-		if include.MatchString(name) {
-			return true
-		}
+func validFile(name string, tests bool, include, exclude *regexp.Regexp) bool {
+	if !strings.HasSuffix(name, ".go") ||
+		!tests && isTestFile(name) {
 		return false
 	}
 
-	if strings.HasSuffix(name, "_test.go") ||
-		!strings.HasSuffix(name, ".go") {
+	if include != nil && !include.MatchString(name) {
 		return false
 	}
 
@@ -108,3 +357,13 @@ func validFile(name string, include, exclude *regexp.Regexp) bool {
 
 	return true
 }
+
+func isTestFile(name string) bool {
+	return strings.HasSuffix(name, "_test.go")
+}
+
+// isExternalTest reports whether test file package is an external test package:
+// its name differs from the production one and has `_test` suffix.
+func isExternalTest(testPackage, prodPackage string) bool {
+	return testPackage != prodPackage && strings.HasSuffix(testPackage, "_test")
+}
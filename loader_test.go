@@ -1,8 +1,13 @@
 package astparser
 
 import (
+	"context"
+	"go/ast"
+	"go/parser"
+	"go/token"
 	"reflect"
 	"regexp"
+	"strings"
 	"testing"
 )
 
@@ -10,6 +15,7 @@ func Test_validFile(t *testing.T) {
 	tests := []struct {
 		name    string
 		s       string
+		tests   bool
 		include *regexp.Regexp
 		exclude *regexp.Regexp
 		want    bool
@@ -36,6 +42,24 @@ func Test_validFile(t *testing.T) {
 			s:    "event_test.go",
 			want: false,
 		},
+		{
+			name:  "test file with tests",
+			s:     "event_test.go",
+			tests: true,
+			want:  true,
+		},
+		{
+			name:    "include test file",
+			s:       "event_test.go",
+			include: regexp.MustCompile("event"),
+			want:    false,
+		},
+		{
+			name:    "include non go file",
+			s:       "event.go.tmpl",
+			include: regexp.MustCompile("event"),
+			want:    false,
+		},
 		{
 			name:    "exclude ok",
 			s:       "event.go",
@@ -48,10 +72,30 @@ func Test_validFile(t *testing.T) {
 			exclude: regexp.MustCompile("type"),
 			want:    true,
 		},
+		{
+			name:    "include and exclude",
+			s:       "event_legacy.go",
+			include: regexp.MustCompile("^event_"),
+			exclude: regexp.MustCompile("^event_legacy"),
+			want:    false,
+		},
+		{
+			name:    "include and exclude dont match",
+			s:       "event_new.go",
+			include: regexp.MustCompile("^event_"),
+			exclude: regexp.MustCompile("^event_legacy"),
+			want:    true,
+		},
+		{
+			name:    "include relative path",
+			s:       "models/event.go",
+			include: regexp.MustCompile("^models/"),
+			want:    true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := validFile(tt.s, tt.include, tt.exclude); got != tt.want {
+			if got := validFile(tt.s, tt.tests, tt.include, tt.exclude); got != tt.want {
 				t.Errorf("validFile() = %v, want %v", got, tt.want)
 			}
 		})
@@ -69,9 +113,11 @@ func Test_parseFile(t *testing.T) {
 			name:     "struct with primitives",
 			filename: "fixtures_test/struct_with_primitives.go",
 			want: ParsedFile{
+				Path: "fixtures_test/struct_with_primitives.go",
 				Structs: []StructDef{
 					{
 						Name: "Primitives",
+						File: "fixtures_test/struct_with_primitives.go",
 						Fields: []FieldDef{
 							{
 								FieldName: "Int",
@@ -183,9 +229,11 @@ func Test_parseFile(t *testing.T) {
 			name:     "struct with dep",
 			filename: "fixtures_test/struct_with_dep.go",
 			want: ParsedFile{
+				Path: "fixtures_test/struct_with_dep.go",
 				Structs: []StructDef{
 					{
 						Name: "Dep",
+						File: "fixtures_test/struct_with_dep.go",
 						Fields: []FieldDef{
 							{
 								FieldType: TypeSimple{Name: "int"},
@@ -197,6 +245,7 @@ func Test_parseFile(t *testing.T) {
 					},
 					{
 						Name: "Dep2",
+						File: "fixtures_test/struct_with_dep.go",
 						Fields: []FieldDef{
 							{
 								FieldType: TypeSimple{Name: "string"},
@@ -208,6 +257,7 @@ func Test_parseFile(t *testing.T) {
 					},
 					{
 						Name: "Struct",
+						File: "fixtures_test/struct_with_dep.go",
 						Fields: []FieldDef{
 							{
 								FieldType: TypeCustom{Name: "Dep"},
@@ -234,14 +284,30 @@ func Test_parseFile(t *testing.T) {
 						},
 					},
 				},
+				Types: []TypeDef{
+					{
+						Name: "StructSlice",
+						Type: TypeArray{InnerType: TypeCustom{Name: "Dep"}},
+						File: "fixtures_test/struct_with_dep.go",
+					},
+					{
+						Name: "MyEnum2",
+						Type: TypeSimple{Name: "string"},
+						File: "fixtures_test/struct_with_dep.go",
+					},
+				},
 				Constants: []ConstantDef{
 					{
 						Name:  "MyEnum21",
 						Value: "1",
+						Type:  "MyEnum2",
+						File:  "fixtures_test/struct_with_dep.go",
 					},
 					{
 						Name:  "MyEnum22",
 						Value: "2",
+						Type:  "MyEnum2",
+						File:  "fixtures_test/struct_with_dep.go",
 					},
 				},
 				Package: "fixtures_test",
@@ -250,24 +316,52 @@ func Test_parseFile(t *testing.T) {
 		{
 			name:     "constants",
 			filename: "fixtures_test/constants.go",
-			want: ParsedFile{Constants: []ConstantDef{
-				{
-					Name:  "PublicConst",
-					Value: "public",
-				},
-				{
-					Name:  "privateConst",
-					Value: "private",
-				},
-				{
-					Name:  "MyEnumValue1",
-					Value: "enum-1",
+			want: ParsedFile{
+				Path: "fixtures_test/constants.go",
+				Types: []TypeDef{
+					{
+						Name: "MyEnum",
+						Type: TypeSimple{Name: "string"},
+						File: "fixtures_test/constants.go",
+					},
+					{
+						Name: "Status",
+						Type: TypeSimple{Name: "int"},
+						File: "fixtures_test/constants.go",
+					},
 				},
-				{
-					Name:  "MyEnumValue2",
-					Value: "enum-2",
+				Constants: []ConstantDef{
+					{
+						Name:  "PublicConst",
+						Value: "public",
+						File:  "fixtures_test/constants.go",
+					},
+					{
+						Name:  "privateConst",
+						Value: "private",
+						File:  "fixtures_test/constants.go",
+					},
+					{
+						Name:  "MyEnumValue1",
+						Value: "enum-1",
+						Type:  "MyEnum",
+						File:  "fixtures_test/constants.go",
+					},
+					{
+						Name:  "MyEnumValue2",
+						Value: "enum-2",
+						Type:  "MyEnum",
+						File:  "fixtures_test/constants.go",
+					},
+					{Name: "DefaultMyEnum", Value: "default", File: "fixtures_test/constants.go"},
+					{Name: "StatusUnknown", Value: "0", Type: "Status", File: "fixtures_test/constants.go"},
+					{Name: "StatusActive", Value: "1", Type: "Status", File: "fixtures_test/constants.go"},
+					{Name: "StatusBlocked", Value: "3", Type: "Status", File: "fixtures_test/constants.go"},
+					{Name: "StatusDeleted", Value: "13", Type: "Status", File: "fixtures_test/constants.go"},
+					{Name: "KB", Value: "1024", File: "fixtures_test/constants.go"},
+					{Name: "MB", Value: "1048576", File: "fixtures_test/constants.go"},
+					{Name: "Half", Value: "0.5", File: "fixtures_test/constants.go"},
 				},
-			},
 				Package: "fixtures_test",
 			},
 		},
@@ -285,3 +379,248 @@ func Test_parseFile(t *testing.T) {
 		})
 	}
 }
+
+func Test_parseFileImports(t *testing.T) {
+	file, err := parseFile("fixtures_test/imports.go")
+	if err != nil {
+		t.Fatalf("parseFile() error = %v", err)
+	}
+
+	want := []ImportDef{
+		{
+			Name:     "_",
+			Path:     "embed",
+			Comments: []string{"embed is imported for go:embed directives."},
+			Pos:      token.Position{Filename: "fixtures_test/imports.go", Offset: 80, Line: 5, Column: 2},
+		},
+		{
+			Name: "js",
+			Path: "encoding/json",
+			Pos:  token.Position{Filename: "fixtures_test/imports.go", Offset: 91, Line: 6, Column: 2},
+		},
+		{
+			Name: ".",
+			Path: "math",
+			Pos:  token.Position{Filename: "fixtures_test/imports.go", Offset: 111, Line: 7, Column: 2},
+		},
+		{
+			Path:     "net/http",
+			Comments: []string{"http is used by Request"},
+			Pos:      token.Position{Filename: "fixtures_test/imports.go", Offset: 121, Line: 8, Column: 2},
+		},
+	}
+	if !reflect.DeepEqual(file.Imports, want) {
+		t.Errorf("\nhave %+v, \nwant %+v", file.Imports, want)
+	}
+
+	qualifiers := map[string]string{"js": "encoding/json", "http": "net/http", "_": "", "math": "", "json": ""}
+	for q, wantPath := range qualifiers {
+		got, ok := file.ImportPath(q)
+		if got != wantPath || ok != (wantPath != "") {
+			t.Errorf("ImportPath(%q) = %q, %v, want %q", q, got, ok, wantPath)
+		}
+	}
+
+	body := file.Structs[0].Fields[0].FieldType.(TypeCustom)
+	if body.Name != "RawMessage" || body.Qualifier != "js" {
+		t.Errorf("unexpected field type %+v", body)
+	}
+}
+
+func Test_parseSourceSelfReferencingTypes(t *testing.T) {
+	src := []byte(`package models
+
+type List []List
+
+type Tree map[string]Tree
+
+type Node struct {
+	Children List
+}
+`)
+	file, err := parseSource(token.NewFileSet(), "models.go", src)
+	if err != nil {
+		t.Fatalf("parseSource() error = %v", err)
+	}
+
+	list := TypeCustom{Name: "List"}
+	want := []Type{
+		TypeArray{InnerType: list},
+		TypeMap{KeyType: TypeSimple{Name: "string"}, ValueType: TypeCustom{Name: "Tree"}},
+	}
+	if len(file.Types) != len(want) {
+		t.Fatalf("unexpected types %+v", file.Types)
+	}
+	for i, td := range file.Types {
+		if !reflect.DeepEqual(td.Type, want[i]) {
+			t.Errorf("%s\nhave %+v, \nwant %+v", td.Name, td.Type, want[i])
+		}
+	}
+
+	list.AliasType = TypeArray{InnerType: TypeCustom{Name: "List"}}
+	if got := file.Structs[0].Fields[0].FieldType; !reflect.DeepEqual(got, list) {
+		t.Errorf("\nhave %+v, \nwant %+v", got, list)
+	}
+}
+
+func Test_parseSourceStructError(t *testing.T) {
+	_, err := parseSource(token.NewFileSet(), "models.go", []byte("package models\n\ntype H struct {\n\tID int `json`\n}\n"))
+	if err == nil || !strings.Contains(err.Error(), "failed to parse struct H") {
+		t.Errorf("parseSource() error = %v", err)
+	}
+}
+
+func Test_parseSourceUnsupportedFields(t *testing.T) {
+	src := []byte(`package models
+
+type Handler func()
+
+type H struct {
+	Fn      func()
+	Done    chan struct{}
+	Handler Handler
+	ID      int
+}
+`)
+	file, err := parseSource(token.NewFileSet(), "models.go", src)
+	if err != nil {
+		t.Fatalf("parseSource() error = %v", err)
+	}
+	want := []FieldDef{
+		{FieldName: "Handler", FieldType: TypeCustom{Name: "Handler"}},
+		{FieldName: "ID", FieldType: TypeSimple{Name: "int"}},
+	}
+	if !reflect.DeepEqual(file.Structs[0].Fields, want) {
+		t.Errorf("\nhave %+v, \nwant %+v", file.Structs[0].Fields, want)
+	}
+}
+
+func TestWalker_typeDoc(t *testing.T) {
+	src := `package models
+
+// Event is a user action.
+type Event struct{}
+
+// Types doc.
+type (
+	// ID is the event id.
+	ID string
+	Kind string
+)
+`
+	file, err := parser.ParseFile(token.NewFileSet(), "models.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	walker := &Walker{}
+	ast.Walk(walker, file)
+
+	if want := []string{"Event is a user action."}; !reflect.DeepEqual(walker.Structs[0].Comments, want) {
+		t.Errorf("\nhave %+v, \nwant %+v", walker.Structs[0].Comments, want)
+	}
+	var comments [][]string
+	for _, typ := range walker.Types {
+		comments = append(comments, typ.Comments)
+	}
+	if want := [][]string{{"ID is the event id."}, nil}; !reflect.DeepEqual(comments, want) {
+		t.Errorf("\nhave %+v, \nwant %+v", comments, want)
+	}
+	if ts := file.Decls[0].(*ast.GenDecl).Specs[0].(*ast.TypeSpec); ts.Doc != nil {
+		t.Errorf("type spec doc is changed to %+v", ts.Doc)
+	}
+}
+
+func Test_importPathToName(t *testing.T) {
+	tests := map[string]string{
+		"time":                        "time",
+		"encoding/json":               "json",
+		"gopkg.in/yaml.v3":            "yaml",
+		"github.com/go-chi/chi/v5":    "chi",
+		"github.com/mattn/go-sqlite3": "sqlite3",
+	}
+	for importPath, want := range tests {
+		if got := importPathToName(importPath); got != want {
+			t.Errorf("importPathToName(%q) = %q, want %q", importPath, got, want)
+		}
+	}
+}
+
+func TestLoadContext_concurrency(t *testing.T) {
+	want, err := Load(Config{InputDir: "fixtures_test", Concurrency: 1})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	fset := token.NewFileSet()
+	got, err := LoadContext(context.Background(), Config{InputDir: "fixtures_test", Concurrency: 8, Fset: fset})
+	if err != nil {
+		t.Fatalf("LoadContext() error = %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("have %d files, want %d", len(got), len(want))
+	}
+	// selector type expressions keep token.Pos which depends on parse order,
+	// so compare the rest.
+	for name, w := range want {
+		g := got[name]
+		if g.Path != w.Path || len(g.Structs) != len(w.Structs) ||
+			!reflect.DeepEqual(g.Imports, w.Imports) || !reflect.DeepEqual(g.Constants, w.Constants) {
+			t.Errorf("parallel load of %s differs from sequential one", name)
+		}
+	}
+
+	var parsed int
+	fset.Iterate(func(*token.File) bool {
+		parsed++
+		return true
+	})
+	if parsed != len(got) {
+		t.Errorf("shared file set has %d files, want %d", parsed, len(got))
+	}
+}
+
+func TestLoadContext_canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := LoadContext(ctx, Config{InputDir: "fixtures_test"}); err != context.Canceled {
+		t.Errorf("LoadContext() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestLoadContext_observer(t *testing.T) {
+	var events []FileEvent
+	cfg := Config{
+		Sources: map[string][]byte{
+			"a.go": []byte("package a\n\ntype A struct{}\n"),
+			"b.go": []byte("package a\n\ntype B struct{}\n"),
+		},
+		Observer: func(e FileEvent) { events = append(events, e) },
+	}
+	if _, err := Load(cfg); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	kinds := map[string][]FileEventKind{}
+	for _, e := range events {
+		if e.Total != 2 {
+			t.Errorf("Total = %d, want 2", e.Total)
+		}
+		kinds[e.Path] = append(kinds[e.Path], e.Kind)
+	}
+	want := map[string][]FileEventKind{"a.go": {FileStarted, FileFinished}, "b.go": {FileStarted, FileFinished}}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Errorf("\nhave %v, \nwant %v", kinds, want)
+	}
+
+	events = nil
+	cfg.Sources["b.go"] = []byte("package a\n\ntype B struct{")
+	cfg.Concurrency = 1
+	if _, err := Load(cfg); err == nil {
+		t.Fatal("expected parse error")
+	}
+	last := events[len(events)-1]
+	if last.Kind != FileFailed || last.Path != "b.go" || last.Err == nil {
+		t.Errorf("unexpected last event %+v", last)
+	}
+}
@@ -0,0 +1,294 @@
+package astparser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Model is a queryable view of loaded packages.
+// Results are sorted by type ID and point into the packages declarations.
+type Model struct {
+	Packages []*Package
+
+	graph *TypeGraph
+}
+
+// NewModel creates a model of the packages.
+func NewModel(packages []*Package) *Model {
+	return &Model{Packages: packages, graph: NewTypeGraph(packages)}
+}
+
+// Graph returns the dependency graph of the model types.
+func (m *Model) Graph() *TypeGraph {
+	return m.graph
+}
+
+// File returns the path of the file the type is declared in.
+func (n *TypeNode) File() string {
+	if n.Struct != nil {
+		return n.Struct.File
+	}
+	return n.Type.File
+}
+
+// Comments returns the type doc comment lines.
+func (n *TypeNode) Comments() []string {
+	if n.Struct != nil {
+		return n.Struct.Comments
+	}
+	return n.Type.Comments
+}
+
+// Annotations returns the type doc comment annotations, see ParseAnnotations.
+func (n *TypeNode) Annotations() map[string]string {
+	return ParseAnnotations(n.Comments())
+}
+
+// TypeID returns ID of the type referenced by the declaration of n like
+// `time.Time` or `example.com/app/models.User`, the type could be not loaded.
+// Predeclared types like `error` are returned as is.
+func (m *Model) TypeID(n *TypeNode, c TypeCustom) string {
+	var file ParsedFile
+	for _, f := range n.Package.Files {
+		if f.Path == n.File() {
+			file = f
+			break
+		}
+	}
+	return customTypeID(file, packageID(n.Package), c)
+}
+
+// Constants returns constants declared with the named type, like enum values
+// of `type Status string`, in declaration order.
+func (m *Model) Constants(n *TypeNode) []ConstantDef {
+	var constants []ConstantDef
+	for _, c := range n.Package.Constants {
+		if c.Type == n.Name {
+			constants = append(constants, c)
+		}
+	}
+	return constants
+}
+
+// FieldRef is a struct field found by a query.
+type FieldRef struct {
+	Struct *TypeNode
+	Field  *FieldDef
+}
+
+// File returns the path of the file the field is declared in.
+func (f FieldRef) File() string {
+	return f.Struct.File()
+}
+
+// Implementer is a type implementing an interface.
+type Implementer struct {
+	Type *TypeNode
+	// Pointer is true if only the pointer to the type implements the interface
+	// because of methods with pointer receivers.
+	Pointer bool
+}
+
+// FindStructs returns structs by ID like `example.com/app/models.Event`
+// or by name, structs of different packages could have the same name.
+func (m *Model) FindStructs(name string) []*TypeNode {
+	return m.filter(func(n *TypeNode) bool {
+		return n.Struct != nil && (n.ID == name || n.Name == name)
+	})
+}
+
+// StructsWithTag returns structs having a field with the tag key like `db`.
+func (m *Model) StructsWithTag(key string) []*TypeNode {
+	return m.filter(func(n *TypeNode) bool {
+		if n.Struct == nil {
+			return false
+		}
+		for _, f := range n.Struct.Fields {
+			if _, ok := f.AllTags[key]; ok {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// FieldsOfType returns fields of all structs with the type rendered like
+// Type.String does, e.g. `time.Time` or `[]*Dep`.
+func (m *Model) FieldsOfType(typ string) []FieldRef {
+	var fields []FieldRef
+	for _, n := range m.graph.Nodes() {
+		if n.Struct == nil {
+			continue
+		}
+		for i := range n.Struct.Fields {
+			f := &n.Struct.Fields[i]
+			if typeString(f.FieldType) == typ {
+				fields = append(fields, FieldRef{Struct: n, Field: f})
+			}
+		}
+	}
+	return fields
+}
+
+// TypesWithAnnotation returns structs and named types annotated with the key,
+// see ParseAnnotations.
+func (m *Model) TypesWithAnnotation(key string) []*TypeNode {
+	return m.filter(func(n *TypeNode) bool {
+		_, ok := n.Annotations()[key]
+		return ok
+	})
+}
+
+// Implementers returns types having all the methods of the interface found
+// by ID or unique name. Methods are matched by name and signature, so the types
+// are expected to reference other packages by the same names. Methods
+// promoted from embedded fields are not taken into account.
+func (m *Model) Implementers(iface string) ([]Implementer, error) {
+	id, err := m.graph.Find(iface)
+	if err != nil {
+		return nil, err
+	}
+	n, _ := m.graph.Node(id)
+	methods, err := m.interfaceMethods(n, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+
+	receivers := m.methodSets()
+	var implementers []Implementer
+	for _, t := range m.graph.Nodes() {
+		if t.ID == id || isInterface(t) {
+			continue
+		}
+
+		value, pointer := true, true
+		set := receivers[t.ID]
+		for name, signature := range methods {
+			method, ok := set[name]
+			if !ok || method.signature != signature {
+				value, pointer = false, false
+				break
+			}
+			if method.pointer {
+				value = false
+			}
+		}
+		if pointer {
+			implementers = append(implementers, Implementer{Type: t, Pointer: !value})
+		}
+	}
+	return implementers, nil
+}
+
+func (m *Model) filter(match func(n *TypeNode) bool) []*TypeNode {
+	var nodes []*TypeNode
+	for _, n := range m.graph.Nodes() {
+		if match(n) {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+func isInterface(n *TypeNode) bool {
+	if n.Type == nil {
+		return false
+	}
+	_, ok := n.Type.Type.(TypeInterfaceValue)
+	return ok
+}
+
+// interfaceMethods returns signatures by name of the interface methods
+// including the ones of embedded interfaces.
+func (m *Model) interfaceMethods(n *TypeNode, seen map[string]bool) (map[string]string, error) {
+	if !isInterface(n) {
+		return nil, fmt.Errorf("type %s is not an interface", n.ID)
+	}
+	seen[n.ID] = true
+
+	methods := map[string]string{}
+	for _, f := range n.Type.Methods {
+		methods[f.Name] = f.Signature
+	}
+	for _, e := range n.Type.Embedded {
+		if e == "error" {
+			methods["Error"] = "func() string"
+			continue
+		}
+
+		id := packageID(n.Package) + "." + e
+		if strings.Contains(e, ".") {
+			// resolve embedded interfaces of other loaded packages.
+			id = e
+			for _, f := range n.Package.Files {
+				if f.Path != n.Type.File {
+					continue
+				}
+				parts := strings.SplitN(e, ".", 2)
+				if importPath, ok := f.ImportPath(parts[0]); ok {
+					id = importPath + "." + parts[1]
+				}
+			}
+		}
+		embedded, ok := m.graph.Node(id)
+		if !ok {
+			return nil, fmt.Errorf("interface %s embeds %s which is not loaded", n.ID, e)
+		}
+		if seen[id] {
+			continue
+		}
+		embeddedMethods, err := m.interfaceMethods(embedded, seen)
+		if err != nil {
+			return nil, err
+		}
+		for name, signature := range embeddedMethods {
+			methods[name] = signature
+		}
+	}
+	return methods, nil
+}
+
+type method struct {
+	signature string
+	pointer   bool
+}
+
+// methodSets returns methods by name by type ID.
+func (m *Model) methodSets() map[string]map[string]method {
+	sets := map[string]map[string]method{}
+	for _, p := range m.Packages {
+		pkg := packageID(p)
+		for _, f := range p.Funcs {
+			if f.Receiver == "" {
+				continue
+			}
+			id := pkg + "." + strings.TrimPrefix(f.Receiver, "*")
+			if sets[id] == nil {
+				sets[id] = map[string]method{}
+			}
+			sets[id][f.Name] = method{signature: f.Signature, pointer: strings.HasPrefix(f.Receiver, "*")}
+		}
+	}
+	return sets
+}
+
+// ParseAnnotations returns annotations of doc comment lines like
+// `+gen` or `+kubebuilder:validation:MaxLength=10` by key, annotations
+// without value have empty values.
+func ParseAnnotations(comments []string) map[string]string {
+	annotations := map[string]string{}
+	for _, c := range comments {
+		c = strings.TrimSpace(c)
+		if !strings.HasPrefix(c, "+") || len(c) == 1 {
+			continue
+		}
+		kv := strings.SplitN(c[1:], "=", 2)
+		key := strings.TrimSpace(kv[0])
+		if len(kv) == 2 {
+			annotations[key] = strings.TrimSpace(kv[1])
+		} else {
+			annotations[key] = ""
+		}
+	}
+	return annotations
+}
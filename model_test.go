@@ -0,0 +1,369 @@
+package astparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+var modelSources = map[string][]byte{
+	"go.mod": []byte("module example.com/app\n"),
+	"models/user.go": []byte(`package models
+
+import (
+	"context"
+	"time"
+)
+
+// User is a stored user.
+// +table=users
+type User struct {
+	ID        int64     ` + "`db:\"id\" json:\"id\"`" + `
+	CreatedAt time.Time ` + "`db:\"created_at\"`" + `
+	Friends   []*User
+}
+
+// +enum
+type Status string
+
+type Event struct {
+	At time.Time ` + "`json:\"at\"`" + `
+}
+
+type Named interface {
+	Name() string
+}
+
+type Store interface {
+	Named
+	Get(ctx context.Context, id int64) (*User, error)
+}
+
+type DB struct{}
+
+func (DB) Name() string { return "db" }
+
+func (d *DB) Get(ctx context.Context, id int64) (*User, error) { return nil, nil }
+
+type Cache struct{}
+
+func (Cache) Name() string { return "cache" }
+
+func (Cache) Get(context.Context, int64) (*User, error) { return nil, nil }
+
+type Broken struct{}
+
+func (Broken) Name() int { return 0 }
+`),
+	"dto/user.go": []byte(`package dto
+
+type User struct {
+	Name string ` + "`json:\"name\"`" + `
+}
+`),
+}
+
+func loadModel(t *testing.T) *Model {
+	packages, err := LoadPackages(Config{InputDirs: []string{"models", "dto"}, Sources: modelSources})
+	if err != nil {
+		t.Fatalf("LoadPackages() error = %v", err)
+	}
+	return NewModel(packages)
+}
+
+func nodeIDs(nodes []*TypeNode) []string {
+	var ids []string
+	for _, n := range nodes {
+		ids = append(ids, n.ID)
+	}
+	return ids
+}
+
+func TestModel_FindStructs(t *testing.T) {
+	m := loadModel(t)
+
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{name: "User", want: []string{"example.com/app/dto.User", "example.com/app/models.User"}},
+		{name: "example.com/app/models.User", want: []string{"example.com/app/models.User"}},
+		{name: "Status"},
+		{name: "Unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nodeIDs(m.FindStructs(tt.name)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\nhave %+v, \nwant %+v", got, tt.want)
+			}
+		})
+	}
+
+	user := m.FindStructs("example.com/app/models.User")[0]
+	if user.File() != "models/user.go" || user.Package.Name != "models" {
+		t.Errorf("unexpected location %s of package %s", user.File(), user.Package.Name)
+	}
+}
+
+func TestModel_StructsWithTag(t *testing.T) {
+	m := loadModel(t)
+
+	if got, want := nodeIDs(m.StructsWithTag("db")), []string{"example.com/app/models.User"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("db\nhave %+v, \nwant %+v", got, want)
+	}
+	want := []string{"example.com/app/dto.User", "example.com/app/models.Event", "example.com/app/models.User"}
+	if got := nodeIDs(m.StructsWithTag("json")); !reflect.DeepEqual(got, want) {
+		t.Errorf("json\nhave %+v, \nwant %+v", got, want)
+	}
+}
+
+func TestModel_FieldsOfType(t *testing.T) {
+	m := loadModel(t)
+
+	var got []string
+	for _, f := range m.FieldsOfType("time.Time") {
+		got = append(got, f.Struct.ID+"."+f.Field.FieldName+" "+f.File())
+	}
+	want := []string{
+		"example.com/app/models.Event.At models/user.go",
+		"example.com/app/models.User.CreatedAt models/user.go",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\nhave %+v, \nwant %+v", got, want)
+	}
+
+	if got := m.FieldsOfType("[]*User"); len(got) != 1 || got[0].Field.FieldName != "Friends" {
+		t.Errorf("unexpected fields %+v", got)
+	}
+}
+
+func TestModel_TypesWithAnnotation(t *testing.T) {
+	m := loadModel(t)
+
+	if got, want := nodeIDs(m.TypesWithAnnotation("enum")), []string{"example.com/app/models.Status"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("enum\nhave %+v, \nwant %+v", got, want)
+	}
+	users := m.TypesWithAnnotation("table")
+	if got, want := nodeIDs(users), []string{"example.com/app/models.User"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("table\nhave %+v, \nwant %+v", got, want)
+	}
+	if got := users[0].Annotations()["table"]; got != "users" {
+		t.Errorf("table annotation = %q, want users", got)
+	}
+}
+
+func TestModel_Implementers(t *testing.T) {
+	m := loadModel(t)
+
+	implementers, err := m.Implementers("Store")
+	if err != nil {
+		t.Fatalf("Implementers() error = %v", err)
+	}
+	got := map[string]bool{}
+	for _, i := range implementers {
+		got[i.Type.ID] = i.Pointer
+	}
+	want := map[string]bool{
+		"example.com/app/models.Cache": false,
+		"example.com/app/models.DB":    true,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Store\nhave %+v, \nwant %+v", got, want)
+	}
+
+	implementers, err = m.Implementers("example.com/app/models.Named")
+	if err != nil {
+		t.Fatalf("Implementers() error = %v", err)
+	}
+	if got, want := len(implementers), 2; got != want {
+		t.Errorf("Named implementers = %d, want %d", got, want)
+	}
+
+	if _, err := m.Implementers("User"); err == nil {
+		t.Error("expected ambiguous name error")
+	}
+	if _, err := m.Implementers("Status"); err == nil {
+		t.Error("expected not an interface error")
+	}
+}
+
+func TestParseAnnotations(t *testing.T) {
+	got := ParseAnnotations([]string{"User is a user.", " +gen", "+key = value", "+", "+a=b=c"})
+	want := map[string]string{"gen": "", "key": "value", "a": "b=c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\nhave %+v, \nwant %+v", got, want)
+	}
+}
+
+func TestModel_JSONFields(t *testing.T) {
+	packages, err := LoadPackages(Config{InputDir: "models", Sources: map[string][]byte{
+		"models/event.go": []byte(`package models
+
+type Event struct {
+	*Base
+	Meta ` + "`json:\"meta,omitempty\"`" + `
+	ID     int64  ` + "`json:\"event_id\" nullable:\"true\"`" + `
+	Name   string
+	hidden string
+	Status
+}
+
+type Base struct {
+	ID      string ` + "`json:\"id\"`" + `
+	Version int    ` + "`json:\"version\"`" + `
+	Name    string
+}
+
+type Meta struct {
+	Source string
+}
+
+type Status string
+
+const (
+	StatusOK  Status = "ok"
+	StatusErr Status = "err"
+)
+`),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewModel(packages)
+	event := m.FindStructs("Event")[0]
+
+	var got []string
+	for _, f := range m.JSONFields(event) {
+		name := f.Struct.Name + "." + f.Name
+		if f.Omitempty {
+			name += ",omitempty"
+		}
+		if f.Nullable {
+			name += ",nullable"
+		}
+		got = append(got, name)
+	}
+	want := []string{"Base.id", "Base.version", "Event.meta,omitempty", "Event.event_id,nullable", "Event.Name", "Event.Status"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\nhave %+v, \nwant %+v", got, want)
+	}
+
+	status, _ := m.Graph().Node(packageID(packages[0]) + ".Status")
+	var values []string
+	for _, c := range m.Constants(status) {
+		values = append(values, c.Value)
+	}
+	if want := []string{"ok", "err"}; !reflect.DeepEqual(values, want) {
+		t.Errorf("Constants()\nhave %+v, \nwant %+v", values, want)
+	}
+}
+
+func TestModel_JSONFieldsConflicts(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []string
+	}{
+		{
+			name: "ambiguous fields are dropped",
+			source: `type E struct {
+	A
+	B
+	Name string ` + "`json:\"name\"`" + `
+}
+type A struct{ ID string }
+type B struct{ ID string }`,
+			want: []string{"E.name"},
+		},
+		{
+			name: "tagged field wins",
+			source: `type E struct {
+	A
+	B
+}
+type A struct{ ID string }
+type B struct{ Key string ` + "`json:\"ID\"`" + ` }`,
+			want: []string{"B.ID"},
+		},
+		{
+			name: "tagged fields are ambiguous",
+			source: `type E struct {
+	A
+	B
+	Name string
+}
+type A struct{ ID string ` + "`json:\"id\"`" + ` }
+type B struct{ Key string ` + "`json:\"id\"`" + ` }`,
+			want: []string{"E.Name"},
+		},
+		{
+			name: "shallower field wins",
+			source: `type E struct {
+	ID string
+	A
+}
+type A struct{ Key string ` + "`json:\"ID\"`" + ` }`,
+			want: []string{"E.ID"},
+		},
+		{
+			name: "struct embedded twice",
+			source: `type E struct {
+	A
+	B
+}
+type A struct{ C }
+type B struct{ C }
+type C struct{ ID string }`,
+			want: nil,
+		},
+		{
+			name: "embedded types which are not loaded are skipped",
+			source: `import "time"
+
+type E struct {
+	time.Time
+	*Base
+	Name string
+}`,
+			want: []string{"E.Name"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			packages, err := LoadPackages(Config{InputDir: "models", Sources: map[string][]byte{
+				"models/e.go": []byte("package models\n\n" + tt.source + "\n"),
+			}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			m := NewModel(packages)
+
+			var got []string
+			for _, f := range m.JSONFields(m.FindStructs("E")[0]) {
+				got = append(got, f.Struct.Name+"."+f.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\nhave %+v, \nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModel_TypeID(t *testing.T) {
+	m := loadModel(t)
+	user := m.FindStructs("example.com/app/models.User")[0]
+
+	tests := []struct {
+		typ  TypeCustom
+		want string
+	}{
+		{typ: TypeCustom{Name: "Time", Qualifier: "time"}, want: "time.Time"},
+		{typ: TypeCustom{Name: "Context", Qualifier: "context"}, want: "context.Context"},
+		{typ: TypeCustom{Name: "Status"}, want: "example.com/app/models.Status"},
+		{typ: TypeCustom{Name: "error"}, want: "error"},
+	}
+	for _, tt := range tests {
+		if got := m.TypeID(user, tt.typ); got != tt.want {
+			t.Errorf("TypeID(%s) = %s, want %s", tt.typ, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package astparser
+
+import (
+	"sync"
+	"time"
+)
+
+// FileEventKind is a kind of file loading event.
+type FileEventKind int
+
+const (
+	FileStarted FileEventKind = iota + 1
+	FileFinished
+	FileFailed
+)
+
+func (k FileEventKind) String() string {
+	switch k {
+	case FileStarted:
+		return "started"
+	case FileFinished:
+		return "finished"
+	case FileFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// FileEvent is passed to Config.Observer for each loaded file.
+type FileEvent struct {
+	Kind FileEventKind
+	// Path is the file path in ParsedFile.Path form.
+	Path string
+	// Total is the number of files in the current load.
+	Total int
+	// Duration is the file parse time, set for finished and failed files.
+	Duration time.Duration
+	// Cached is true if the file was taken from Config.CacheDir.
+	Cached bool
+	// Err is set for failed files.
+	Err error
+}
+
+// newObserver wraps observer to serialize its calls, it never returns nil.
+func newObserver(observer func(FileEvent), total int) func(FileEvent) {
+	if observer == nil {
+		return func(FileEvent) {}
+	}
+
+	var mu sync.Mutex
+	return func(e FileEvent) {
+		e.Total = total
+		mu.Lock()
+		defer mu.Unlock()
+		observer(e)
+	}
+}
@@ -0,0 +1,250 @@
+package astparser
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DeclKind is a kind of package level declaration.
+type DeclKind int
+
+const (
+	DeclStruct DeclKind = iota + 1
+	DeclType
+	DeclConst
+	DeclFunc
+)
+
+// Decl is a package level declaration found by Package.Lookup.
+// Only one of Struct, Type, Const or Func is set depending on Kind.
+type Decl struct {
+	Kind DeclKind
+	Name string
+	// File is a path of the file the declaration came from.
+	File   string
+	Struct *StructDef
+	Type   *TypeDef
+	Const  *ConstantDef
+	Func   *FuncDef
+}
+
+// Package aggregates declarations of all the parsed files of a single go package.
+type Package struct {
+	Name string `json:"name"`
+	// ImportPath is resolved from the nearest go.mod, empty if there is none.
+	ImportPath string `json:"import_path"`
+	Dir        string `json:"dir"`
+	// Test is true for packages made of `_test.go` files. In-package tests
+	// have the same name as the production package, external ones have `_test` suffix.
+	Test bool     `json:"test"`
+	Doc  []string `json:"doc"`
+	// Files are sorted by path. Other declarations are collected from them
+	// and are not encoded to JSON.
+	Files     []ParsedFile  `json:"files"`
+	Structs   []StructDef   `json:"-"`
+	Types     []TypeDef     `json:"-"`
+	Constants []ConstantDef `json:"-"`
+	Funcs     []FuncDef     `json:"-"`
+	// Imports contains distinct sorted import paths of all the package files.
+	Imports []string `json:"-"`
+
+	index map[string]Decl
+}
+
+// Lookup finds package level declaration by name.
+// Methods are looked up by `Type.Method` name.
+func (p *Package) Lookup(name string) (Decl, bool) {
+	if p.index == nil {
+		p.buildIndex()
+	}
+	d, ok := p.index[name]
+	return d, ok
+}
+
+// LoadPackages parses files like Load does and groups them into packages.
+// Test files form separate packages marked with Test flag.
+// Packages are sorted by name, production packages go before test ones.
+func LoadPackages(cfg Config) ([]*Package, error) {
+	return LoadPackagesContext(context.Background(), cfg)
+}
+
+// LoadPackagesContext is like LoadPackages but stops parsing once ctx is done.
+func LoadPackagesContext(ctx context.Context, cfg Config) ([]*Package, error) {
+	if err := cfg.prepare(); err != nil {
+		return nil, errors.Wrap(err, "invalid config")
+	}
+	// files of several input dirs could have the same names, so they are keyed by path.
+	files, err := loadFiles(ctx, cfg, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return newPackages(newImportPathResolver(newSources(cfg)), files)
+}
+
+// newPackages groups files into packages by dir, package name and test kind.
+func newPackages(resolver *importPathResolver, files map[string]ParsedFile) ([]*Package, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	type packageKey struct {
+		dir  string
+		name string
+		test bool
+	}
+	byKey := map[packageKey]*Package{}
+	var packages []*Package
+	for _, name := range names {
+		f := files[name]
+		key := packageKey{dir: filepath.Dir(f.Path), name: f.Package, test: f.IsTest}
+		p, ok := byKey[key]
+		if !ok {
+			importPath, err := resolver.resolve(key.dir)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to resolve import path of %s", key.dir)
+			}
+			p = &Package{Name: f.Package, Dir: key.dir, ImportPath: importPath, Test: f.IsTest}
+			byKey[key] = p
+			packages = append(packages, p)
+		}
+		p.addFile(f)
+	}
+
+	// production package names by dir.
+	prodPackages := map[string]string{}
+	for _, p := range packages {
+		if !p.Test {
+			prodPackages[p.Dir] = p.Name
+		}
+	}
+	// external test packages get `_test` suffix like `go list` does.
+	for _, p := range packages {
+		if p.Test && p.ImportPath != "" && isExternalTest(p.Name, prodPackages[p.Dir]) {
+			p.ImportPath += "_test"
+		}
+	}
+
+	for _, p := range packages {
+		p.buildIndex()
+	}
+
+	sort.Slice(packages, func(i, j int) bool {
+		if packages[i].Dir != packages[j].Dir {
+			return packages[i].Dir < packages[j].Dir
+		}
+		if packages[i].Name != packages[j].Name {
+			return packages[i].Name < packages[j].Name
+		}
+		return !packages[i].Test && packages[j].Test
+	})
+	return packages, nil
+}
+
+func (p *Package) addFile(f ParsedFile) {
+	p.Files = append(p.Files, f)
+	p.Structs = append(p.Structs, f.Structs...)
+	p.Types = append(p.Types, f.Types...)
+	p.Constants = append(p.Constants, f.Constants...)
+	p.Funcs = append(p.Funcs, f.Funcs...)
+	if len(p.Doc) == 0 {
+		p.Doc = f.PackageDoc
+	}
+
+	for _, i := range f.Imports {
+		n := sort.SearchStrings(p.Imports, i.Path)
+		if n < len(p.Imports) && p.Imports[n] == i.Path {
+			continue
+		}
+		p.Imports = append(p.Imports, "")
+		copy(p.Imports[n+1:], p.Imports[n:])
+		p.Imports[n] = i.Path
+	}
+}
+
+func (p *Package) buildIndex() {
+	p.index = map[string]Decl{}
+	for i := range p.Structs {
+		s := &p.Structs[i]
+		p.index[s.Name] = Decl{Kind: DeclStruct, Name: s.Name, File: s.File, Struct: s}
+	}
+	for i := range p.Types {
+		t := &p.Types[i]
+		p.index[t.Name] = Decl{Kind: DeclType, Name: t.Name, File: t.File, Type: t}
+	}
+	for i := range p.Constants {
+		c := &p.Constants[i]
+		p.index[c.Name] = Decl{Kind: DeclConst, Name: c.Name, File: c.File, Const: c}
+	}
+	for i := range p.Funcs {
+		f := &p.Funcs[i]
+		// init funcs could be declared many times and can't be referenced.
+		if f.Receiver == "" && f.Name == "init" {
+			continue
+		}
+		name := f.Name
+		if f.Receiver != "" {
+			name = strings.TrimPrefix(f.Receiver, "*") + "." + f.Name
+		}
+		p.index[name] = Decl{Kind: DeclFunc, Name: name, File: f.File, Func: f}
+	}
+}
+
+// resolveImportPath looks for go.mod in dir and its parents and
+// builds dir import path from the module path.
+func resolveImportPath(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for d := abs; ; d = filepath.Dir(d) {
+		data, err := ioutil.ReadFile(filepath.Join(d, "go.mod"))
+		switch {
+		case err == nil:
+			module := modulePath(data)
+			if module == "" {
+				return "", nil
+			}
+			rel, err := filepath.Rel(d, abs)
+			if err != nil {
+				return "", err
+			}
+			return path.Join(module, filepath.ToSlash(rel)), nil
+		case !os.IsNotExist(err):
+			return "", err
+		}
+
+		if filepath.Dir(d) == d {
+			return "", nil
+		}
+	}
+}
+
+// modulePath returns the module path from go.mod content.
+func modulePath(mod []byte) string {
+	s := bufio.NewScanner(bytes.NewReader(mod))
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) < 2 || fields[0] != "module" {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(fields[1]); err == nil {
+			return unquoted
+		}
+		return fields[1]
+	}
+	return ""
+}
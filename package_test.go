@@ -0,0 +1,261 @@
+package astparser
+
+import (
+	"go/token"
+	"reflect"
+	"testing"
+)
+
+func TestLoadPackages(t *testing.T) {
+	packages, err := LoadPackages(Config{InputDir: "fixtures_test"})
+	if err != nil {
+		t.Fatalf("LoadPackages() error = %v", err)
+	}
+	if len(packages) != 1 {
+		t.Fatalf("expected 1 package, got %d", len(packages))
+	}
+
+	p := packages[0]
+	if p.Name != "fixtures_test" {
+		t.Errorf("Name = %q, want fixtures_test", p.Name)
+	}
+	if p.ImportPath != "github.com/mkorolyov/astparser/fixtures_test" {
+		t.Errorf("ImportPath = %q", p.ImportPath)
+	}
+	if want := []string{"Package fixtures_test contains types for astparser tests."}; !reflect.DeepEqual(p.Doc, want) {
+		t.Errorf("Doc = %v, want %v", p.Doc, want)
+	}
+	if want := []string{"embed", "encoding/json", "math", "net/http", "strings", "time"}; !reflect.DeepEqual(p.Imports, want) {
+		t.Errorf("Imports = %v, want %v", p.Imports, want)
+	}
+
+	tests := []struct {
+		name string
+		kind DeclKind
+		file string
+	}{
+		{name: "Primitives", kind: DeclStruct, file: "fixtures_test/struct_with_primitives.go"},
+		{name: "MyEnum", kind: DeclType, file: "fixtures_test/constants.go"},
+		{name: "MyEnum21", kind: DeclConst, file: "fixtures_test/struct_with_dep.go"},
+		{name: "NewEvent", kind: DeclFunc, file: "fixtures_test/doc.go"},
+		{name: "Event.Title", kind: DeclFunc, file: "fixtures_test/doc.go"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, ok := p.Lookup(tt.name)
+			if !ok {
+				t.Fatalf("%s not found", tt.name)
+			}
+			if d.Kind != tt.kind || d.File != tt.file {
+				t.Errorf("have kind %v file %s, want kind %v file %s", d.Kind, d.File, tt.kind, tt.file)
+			}
+		})
+	}
+
+	if _, ok := p.Lookup("local"); ok {
+		t.Errorf("function local constant should not be indexed")
+	}
+}
+
+func Test_modulePath(t *testing.T) {
+	tests := []struct {
+		name string
+		mod  string
+		want string
+	}{
+		{name: "plain", mod: "module github.com/a/b\n\ngo 1.16\n", want: "github.com/a/b"},
+		{name: "quoted", mod: "// comment\nmodule \"github.com/a/b\"\n", want: "github.com/a/b"},
+		{name: "missing", mod: "go 1.16\n", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := modulePath([]byte(tt.mod)); got != tt.want {
+				t.Errorf("modulePath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadPackages_buildContext(t *testing.T) {
+	tests := []struct {
+		name           string
+		cfg            Config
+		platformFile   string
+		wantConstraint string
+		wantLegacy     bool
+	}{
+		{
+			name:         "windows",
+			cfg:          Config{GOOS: "windows", GOARCH: "amd64"},
+			platformFile: "fixtures_test/platform_windows.go",
+		},
+		{
+			name:           "linux",
+			cfg:            Config{GOOS: "linux", GOARCH: "amd64"},
+			platformFile:   "fixtures_test/platform_other.go",
+			wantConstraint: "!windows && (amd64 || arm64)",
+			wantLegacy:     true,
+		},
+		{
+			name:       "linux 386",
+			cfg:        Config{GOOS: "linux", GOARCH: "386"},
+			wantLegacy: true,
+		},
+		{
+			name:         "custom tag",
+			cfg:          Config{GOOS: "darwin", GOARCH: "arm64", BuildTags: []string{"appengine"}},
+			platformFile: "fixtures_test/platform_other.go",
+			// the constraint is recorded as is, tags don't affect it.
+			wantConstraint: "!windows && (amd64 || arm64)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.InputDir = "fixtures_test"
+			packages, err := LoadPackages(tt.cfg)
+			if err != nil {
+				t.Fatalf("LoadPackages() error = %v", err)
+			}
+			// generate.go with `//go:build ignore` would add package main.
+			if len(packages) != 1 {
+				t.Fatalf("expected 1 package, got %d", len(packages))
+			}
+
+			p := packages[0]
+			d, ok := p.Lookup("Platform")
+			if ok != (tt.platformFile != "") || d.File != tt.platformFile {
+				t.Errorf("Platform found %v in %q, want %q", ok, d.File, tt.platformFile)
+			}
+			if ok {
+				for _, f := range p.Files {
+					if f.Path == d.File && f.BuildConstraint != tt.wantConstraint {
+						t.Errorf("BuildConstraint = %q, want %q", f.BuildConstraint, tt.wantConstraint)
+					}
+				}
+			}
+
+			cfg := tt.cfg
+			cfg.InputDir, cfg.Sources = "legacy", map[string][]byte{"legacy/legacy_tag.go": legacySource}
+			files, err := Load(cfg)
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if _, ok := files["legacy_tag.go"]; ok != tt.wantLegacy {
+				t.Errorf("Legacy found %v, want %v", ok, tt.wantLegacy)
+			}
+		})
+	}
+}
+
+// legacySource has only legacy `// +build` lines. It is not a fixture file
+// because gofmt would add a `//go:build` line to it.
+var legacySource = []byte("// +build linux darwin\n// +build !appengine\n\npackage legacy\n\ntype Legacy struct{}\n")
+
+func Test_parseBuildConstraint(t *testing.T) {
+	file, err := parseSource(token.NewFileSet(), "legacy_tag.go", legacySource)
+	if err != nil {
+		t.Fatalf("parseSource() error = %v", err)
+	}
+	if want := "(linux || darwin) && !appengine"; file.BuildConstraint != want {
+		t.Errorf("BuildConstraint = %q, want %q", file.BuildConstraint, want)
+	}
+}
+
+func TestLoadPackages_tests(t *testing.T) {
+	type pkg struct {
+		Name       string
+		ImportPath string
+		Test       bool
+		Structs    []string
+	}
+	prod := pkg{Name: "fixtures_test", ImportPath: "github.com/mkorolyov/astparser/fixtures_test"}
+	inPackage := pkg{
+		Name:       "fixtures_test",
+		ImportPath: "github.com/mkorolyov/astparser/fixtures_test",
+		Test:       true,
+		Structs:    []string{"EventFixture"},
+	}
+	external := pkg{
+		Name:       "fixtures_test_test",
+		ImportPath: "github.com/mkorolyov/astparser/fixtures_test_test",
+		Test:       true,
+		Structs:    []string{"ExternalFixture"},
+	}
+
+	tests := []struct {
+		name string
+		cfg  Config
+		want []pkg
+	}{
+		{name: "no tests", cfg: Config{}, want: []pkg{prod}},
+		{name: "in-package tests", cfg: Config{IncludeTests: true}, want: []pkg{prod, inPackage}},
+		{name: "external tests", cfg: Config{IncludeExternalTests: true}, want: []pkg{prod, external}},
+		{
+			name: "all tests",
+			cfg:  Config{IncludeTests: true, IncludeExternalTests: true},
+			want: []pkg{prod, inPackage, external},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.InputDir = "fixtures_test"
+			tt.cfg.IncludeRegexp = "^(event_test|external|doc)"
+			packages, err := LoadPackages(tt.cfg)
+			if err != nil {
+				t.Fatalf("LoadPackages() error = %v", err)
+			}
+
+			var got []pkg
+			for _, p := range packages {
+				g := pkg{Name: p.Name, ImportPath: p.ImportPath, Test: p.Test}
+				for _, s := range p.Structs {
+					if s.Name != "Event" {
+						g.Structs = append(g.Structs, s.Name)
+					}
+				}
+				got = append(got, g)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\nhave %+v, \nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadPackages_skipGenerated(t *testing.T) {
+	for _, skip := range []bool{false, true} {
+		packages, err := LoadPackages(Config{InputDir: "fixtures_test", SkipGenerated: skip})
+		if err != nil {
+			t.Fatalf("LoadPackages() error = %v", err)
+		}
+
+		d, ok := packages[0].Lookup("easyjsonEvent")
+		if ok == skip {
+			t.Errorf("SkipGenerated %v: easyjsonEvent found %v", skip, ok)
+		}
+		for _, f := range packages[0].Files {
+			if want := f.Path == d.File && ok; f.IsGenerated != want {
+				t.Errorf("%s IsGenerated = %v, want %v", f.Path, f.IsGenerated, want)
+			}
+		}
+	}
+}
+
+func TestLoadPackages_skipGeneratedUnparsed(t *testing.T) {
+	sources := map[string][]byte{
+		"models/event.go": []byte("package models\n\ntype Event struct{}\n"),
+		"models/event_gen.go": []byte("// Code generated by gen. DO NOT EDIT.\n\npackage models\n\n" +
+			"type Broken struct {\n\tID int `json`\n}\n\nfunc {\n"),
+	}
+	packages, err := LoadPackages(Config{InputDir: "models", Sources: sources, SkipGenerated: true})
+	if err != nil {
+		t.Fatalf("LoadPackages() error = %v", err)
+	}
+	if len(packages) != 1 || len(packages[0].Files) != 1 || packages[0].Files[0].Path != "models/event.go" {
+		t.Errorf("unexpected packages %+v", packages)
+	}
+
+	if _, err := LoadPackages(Config{InputDir: "models", Sources: sources}); err == nil {
+		t.Error("expected parse error of the generated file")
+	}
+}
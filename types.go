@@ -0,0 +1,129 @@
+package astparser
+
+// Kind is a kind of Type.
+type Kind int
+
+const (
+	KindSimple Kind = iota + 1
+	KindArray
+	KindMap
+	KindCustom
+	KindPointer
+	KindInterface
+)
+
+var kindNames = map[Kind]string{
+	KindSimple:    "simple",
+	KindArray:     "array",
+	KindMap:       "map",
+	KindCustom:    "custom",
+	KindPointer:   "pointer",
+	KindInterface: "interface",
+}
+
+func (k Kind) String() string {
+	if name, ok := kindNames[k]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// parseKind returns the kind by its name.
+func parseKind(name string) (Kind, bool) {
+	for k, n := range kindNames {
+		if n == name {
+			return k, true
+		}
+	}
+	return 0, false
+}
+
+func (TypeSimple) Kind() Kind         { return KindSimple }
+func (TypeArray) Kind() Kind          { return KindArray }
+func (TypeMap) Kind() Kind            { return KindMap }
+func (TypeCustom) Kind() Kind         { return KindCustom }
+func (TypePointer) Kind() Kind        { return KindPointer }
+func (TypeInterfaceValue) Kind() Kind { return KindInterface }
+
+func (TypeSimple) isType()         {}
+func (TypeArray) isType()          {}
+func (TypeMap) isType()            {}
+func (TypeCustom) isType()         {}
+func (TypePointer) isType()        {}
+func (TypeInterfaceValue) isType() {}
+
+func (t TypeSimple) String() string {
+	return t.Name
+}
+
+func (t TypeArray) String() string {
+	return "[]" + typeString(t.InnerType)
+}
+
+func (t TypeMap) String() string {
+	return "map[" + typeString(t.KeyType) + "]" + typeString(t.ValueType)
+}
+
+// String renders the type as it is referenced, like `Dep` or `time.Time`.
+func (t TypeCustom) String() string {
+	if t.Qualifier != "" {
+		return t.Qualifier + "." + t.Name
+	}
+	return t.Name
+}
+
+func (t TypePointer) String() string {
+	return "*" + typeString(t.InnerType)
+}
+
+func (TypeInterfaceValue) String() string {
+	return "interface{}"
+}
+
+func (t TypeSimple) Equal(other Type) bool {
+	o, ok := other.(TypeSimple)
+	return ok && t == o
+}
+
+func (t TypeArray) Equal(other Type) bool {
+	o, ok := other.(TypeArray)
+	return ok && equalTypes(t.InnerType, o.InnerType)
+}
+
+func (t TypeMap) Equal(other Type) bool {
+	o, ok := other.(TypeMap)
+	return ok && equalTypes(t.KeyType, o.KeyType) && equalTypes(t.ValueType, o.ValueType)
+}
+
+// Equal compares custom types by name, qualifier and alias type, Expr is ignored.
+func (t TypeCustom) Equal(other Type) bool {
+	o, ok := other.(TypeCustom)
+	return ok && t.Name == o.Name && t.Qualifier == o.Qualifier &&
+		t.Alias == o.Alias && equalTypes(t.AliasType, o.AliasType)
+}
+
+func (t TypePointer) Equal(other Type) bool {
+	o, ok := other.(TypePointer)
+	return ok && equalTypes(t.InnerType, o.InnerType)
+}
+
+func (TypeInterfaceValue) Equal(other Type) bool {
+	_, ok := other.(TypeInterfaceValue)
+	return ok
+}
+
+// typeString is Type.String for possibly nil type.
+func typeString(t Type) string {
+	if t == nil {
+		return ""
+	}
+	return t.String()
+}
+
+// equalTypes is Type.Equal for possibly nil types.
+func equalTypes(a, b Type) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(b)
+}
@@ -0,0 +1,95 @@
+package astparser
+
+import (
+	"go/ast"
+	"testing"
+)
+
+func TestType_String(t *testing.T) {
+	tests := []struct {
+		typ  Type
+		want string
+	}{
+		{typ: TypeSimple{Name: "int64"}, want: "int64"},
+		{typ: TypeArray{InnerType: TypeSimple{Name: "byte"}}, want: "[]byte"},
+		{typ: TypeCustom{Name: "Time", Qualifier: "time"}, want: "time.Time"},
+		{typ: TypePointer{InnerType: TypeCustom{Name: "Dep"}}, want: "*Dep"},
+		{typ: TypeInterfaceValue{}, want: "interface{}"},
+		{
+			typ: TypeMap{
+				KeyType:   TypeSimple{Name: "string"},
+				ValueType: TypeArray{InnerType: TypePointer{InnerType: TypeCustom{Name: "Dep"}}},
+			},
+			want: "map[string][]*Dep",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.typ.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestType_Equal(t *testing.T) {
+	timeType := TypeCustom{Name: "Time", Qualifier: "time"}
+	tests := []struct {
+		name string
+		a, b Type
+		want bool
+	}{
+		{name: "simple", a: TypeSimple{Name: "int"}, b: TypeSimple{Name: "int"}, want: true},
+		{name: "simple names", a: TypeSimple{Name: "int"}, b: TypeSimple{Name: "uint"}, want: false},
+		{name: "kinds", a: TypeSimple{Name: "Dep"}, b: TypeCustom{Name: "Dep"}, want: false},
+		{
+			name: "custom expr ignored",
+			a:    timeType,
+			b:    TypeCustom{Name: "Time", Qualifier: "time", Expr: &ast.SelectorExpr{X: ast.NewIdent("time"), Sel: ast.NewIdent("Time")}},
+			want: true,
+		},
+		{name: "custom qualifier", a: timeType, b: TypeCustom{Name: "Time"}, want: false},
+		{
+			name: "custom alias type",
+			a:    TypeCustom{Name: "MyEnum", AliasType: TypeSimple{Name: "string"}},
+			b:    TypeCustom{Name: "MyEnum", AliasType: TypeSimple{Name: "int"}},
+			want: false,
+		},
+		{
+			name: "map",
+			a:    TypeMap{KeyType: TypeSimple{Name: "string"}, ValueType: TypePointer{InnerType: timeType}},
+			b:    TypeMap{KeyType: TypeSimple{Name: "string"}, ValueType: TypePointer{InnerType: timeType}},
+			want: true,
+		},
+		{
+			name: "map value",
+			a:    TypeMap{KeyType: TypeSimple{Name: "string"}, ValueType: TypePointer{InnerType: timeType}},
+			b:    TypeMap{KeyType: TypeSimple{Name: "string"}, ValueType: timeType},
+			want: false,
+		},
+		{name: "array", a: TypeArray{InnerType: TypeInterfaceValue{}}, b: TypeArray{InnerType: TypeInterfaceValue{}}, want: true},
+		{name: "nil inner", a: TypePointer{}, b: TypePointer{InnerType: timeType}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.Equal(tt.b); got != tt.want {
+				t.Errorf("Equal() = %v, want %v", got, tt.want)
+			}
+			if got := tt.b.Equal(tt.a); got != tt.want {
+				t.Errorf("reversed Equal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKind_String(t *testing.T) {
+	for k := KindSimple; k <= KindInterface; k++ {
+		parsed, ok := parseKind(k.String())
+		if !ok || parsed != k {
+			t.Errorf("parseKind(%q) = %v, %v", k.String(), parsed, ok)
+		}
+	}
+	if got := (TypeMap{}).Kind(); got != KindMap {
+		t.Errorf("Kind() = %v, want %v", got, KindMap)
+	}
+}
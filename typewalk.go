@@ -0,0 +1,90 @@
+package astparser
+
+// TypeVisitor's Visit method is invoked for each type encountered by WalkType.
+// If the result visitor w is not nil, WalkType visits each of the children
+// of the type with the visitor w, followed by a call of w.Visit(nil).
+type TypeVisitor interface {
+	Visit(t Type) (w TypeVisitor)
+}
+
+// WalkType traverses a type tree in depth-first order like ast.Walk does:
+// it starts by calling v.Visit(t). Children are array and pointer inner types,
+// map key and value types and custom types AliasType, so alias chains are walked too.
+func WalkType(v TypeVisitor, t Type) {
+	if t == nil {
+		return
+	}
+	if v = v.Visit(t); v == nil {
+		return
+	}
+
+	switch t := t.(type) {
+	case TypeArray:
+		WalkType(v, t.InnerType)
+	case TypeMap:
+		WalkType(v, t.KeyType)
+		WalkType(v, t.ValueType)
+	case TypePointer:
+		WalkType(v, t.InnerType)
+	case TypeCustom:
+		WalkType(v, t.AliasType)
+	}
+
+	v.Visit(nil)
+}
+
+type typeInspector func(Type) bool
+
+func (f typeInspector) Visit(t Type) TypeVisitor {
+	if f(t) {
+		return f
+	}
+	return nil
+}
+
+// InspectType traverses a type tree in depth-first order like ast.Inspect does:
+// it starts by calling f(t), t is not nil. If f returns true, InspectType invokes f
+// recursively for each of the children of t, followed by a call of f(nil).
+func InspectType(t Type, f func(Type) bool) {
+	WalkType(typeInspector(f), t)
+}
+
+// RewriteType returns a copy of the type tree with every type replaced by f(t).
+// Children are rewritten before their parent, so f gets the parent with
+// rewritten children. Returning t keeps the type as is.
+func RewriteType(t Type, f func(Type) Type) Type {
+	if t == nil {
+		return nil
+	}
+
+	switch v := t.(type) {
+	case TypeArray:
+		v.InnerType = RewriteType(v.InnerType, f)
+		t = v
+	case TypeMap:
+		v.KeyType = RewriteType(v.KeyType, f)
+		v.ValueType = RewriteType(v.ValueType, f)
+		t = v
+	case TypePointer:
+		v.InnerType = RewriteType(v.InnerType, f)
+		t = v
+	case TypeCustom:
+		v.AliasType = RewriteType(v.AliasType, f)
+		t = v
+	}
+
+	return f(t)
+}
+
+// CustomTypes returns all custom types referenced by the type tree
+// including the ones of alias chains, in depth-first order.
+func CustomTypes(t Type) []TypeCustom {
+	var types []TypeCustom
+	InspectType(t, func(t Type) bool {
+		if c, ok := t.(TypeCustom); ok {
+			types = append(types, c)
+		}
+		return true
+	})
+	return types
+}
@@ -0,0 +1,99 @@
+package astparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// typeTree is `map[string][]*Dep` where Dep is an alias of `[]time.Time`.
+var typeTree = TypeMap{
+	KeyType: TypeSimple{Name: "string"},
+	ValueType: TypeArray{InnerType: TypePointer{InnerType: TypeCustom{
+		Name:      "Dep",
+		AliasType: TypeArray{InnerType: TypeCustom{Name: "Time", Qualifier: "time"}},
+	}}},
+}
+
+type kindsVisitor struct {
+	kinds *[]string
+}
+
+func (v kindsVisitor) Visit(t Type) TypeVisitor {
+	if t == nil {
+		*v.kinds = append(*v.kinds, "end")
+		return nil
+	}
+	*v.kinds = append(*v.kinds, t.Kind().String())
+	return v
+}
+
+func TestWalkType(t *testing.T) {
+	var kinds []string
+	WalkType(kindsVisitor{kinds: &kinds}, typeTree)
+
+	want := []string{
+		"map", "simple", "end",
+		"array", "pointer", "custom", "array", "custom", "end", "end", "end", "end", "end",
+		"end",
+	}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Errorf("\nhave %+v, \nwant %+v", kinds, want)
+	}
+}
+
+func TestInspectType(t *testing.T) {
+	var visited []string
+	InspectType(typeTree, func(t Type) bool {
+		if t == nil {
+			return false
+		}
+		visited = append(visited, t.String())
+		// don't go into the alias chain.
+		return t.Kind() != KindCustom
+	})
+
+	want := []string{"map[string][]*Dep", "string", "[]*Dep", "*Dep", "Dep"}
+	if !reflect.DeepEqual(visited, want) {
+		t.Errorf("\nhave %+v, \nwant %+v", visited, want)
+	}
+}
+
+func TestCustomTypes(t *testing.T) {
+	var names []string
+	for _, c := range CustomTypes(typeTree) {
+		names = append(names, c.String())
+	}
+	if want := []string{"Dep", "time.Time"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("\nhave %+v, \nwant %+v", names, want)
+	}
+}
+
+func TestRewriteType(t *testing.T) {
+	timeType := TypeCustom{Name: "Time", Qualifier: "time"}
+	got := RewriteType(typeTree, func(t Type) Type {
+		if t.Equal(timeType) {
+			return TypeSimple{Name: "string"}
+		}
+		return t
+	})
+
+	want := TypeMap{
+		KeyType: TypeSimple{Name: "string"},
+		ValueType: TypeArray{InnerType: TypePointer{InnerType: TypeCustom{
+			Name:      "Dep",
+			AliasType: TypeArray{InnerType: TypeSimple{Name: "string"}},
+		}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\nhave %+v, \nwant %+v", got, want)
+	}
+
+	// the original tree is not changed.
+	if len(CustomTypes(typeTree)) != 2 {
+		t.Errorf("original type is modified: %v", typeTree)
+	}
+
+	if got := RewriteType(nil, func(t Type) Type { return t }); got != nil {
+		t.Errorf("RewriteType(nil) = %v", got)
+	}
+}
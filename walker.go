@@ -3,19 +3,41 @@ package astparser
 import (
 	"fmt"
 	"go/ast"
-	"log"
+	"go/build/constraint"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"path"
+	"regexp"
+	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/pkg/errors"
 )
 
-// TODO parse type comments
 // Walker implements go/ast.Visitor to walk through golang
 // structs and constants to parse them.
 type Walker struct {
-	Structs   []StructDef
-	Constants []ConstantDef
-	Package   string
+	Structs    []StructDef
+	Types      []TypeDef
+	Constants  []ConstantDef
+	Funcs      []FuncDef
+	Imports    []ImportDef
+	Package    string
+	PackageDoc []string
+	// BuildConstraint is the file `//go:build` expression.
+	BuildConstraint string
+	IsGenerated     bool
+	// Err is the first error of the walked declarations, like an invalid struct tag.
+	Err error
+
+	// file is set to each parsed declaration.
+	file string
+	// fset is used to resolve declarations positions, could be nil.
+	fset *token.FileSet
+	// constants are values of the file constants by name to evaluate the ones referencing them.
+	constants map[string]constant.Value
 }
 
 // A Walkers's Visit method is invoked for each node encountered by go/ast.Walk.
@@ -23,37 +45,278 @@ type Walker struct {
 // of node with the visitor w, followed by a call of w.Visit(nil).
 func (w *Walker) Visit(node ast.Node) ast.Visitor {
 	switch spec := node.(type) {
+	case *ast.GenDecl:
+		switch spec.Tok {
+		case token.CONST:
+			w.visitConstants(spec)
+			return nil
+		case token.VAR:
+			w.visitVars(spec)
+			return nil
+		case token.TYPE:
+			for _, s := range spec.Specs {
+				ts, ok := s.(*ast.TypeSpec)
+				if !ok {
+					continue
+				}
+				// doc of a single type declaration like `// Doc\ntype A struct{}`
+				// belongs to the declaration, not to the spec.
+				doc := ts.Doc
+				if doc == nil && !spec.Lparen.IsValid() {
+					doc = spec.Doc
+				}
+				w.visitTypeSpec(ts, doc)
+			}
+			return nil
+		}
 	case *ast.TypeSpec:
-		w.visitTypeSpec(spec)
+		w.visitTypeSpec(spec, spec.Doc)
+		return nil
+	case *ast.ImportSpec:
+		w.visitImport(spec)
+		return nil
+	case *ast.FuncDecl:
+		w.visitFunc(spec)
+		// don't walk into function bodies, local declarations are not a part of package API.
 		return nil
-	case *ast.ValueSpec:
-		w.visitConstant(spec)
 	case *ast.File:
 		w.Package = spec.Name.String()
+		w.PackageDoc = parseComments(spec.Doc)
+		w.BuildConstraint = parseBuildConstraint(spec)
+		w.IsGenerated = isGenerated(spec)
 	}
 
 	return w
 }
 
-func (w *Walker) visitConstant(astValueSpec *ast.ValueSpec) {
-	if len(astValueSpec.Names) < 1 || len(astValueSpec.Values) < 1 {
-		return
+// visitConstants records constants of the const declaration. Specs without values
+// repeat the type and the expressions of the previous ones, so iota enums like
+// `StatusActive Status = iota; StatusBlocked` are recorded with their values.
+// Constants with values which are not computable from literals, iota and
+// the file constants declared before are skipped.
+func (w *Walker) visitConstants(decl *ast.GenDecl) {
+	var typ ast.Expr
+	var values []ast.Expr
+	for i, s := range decl.Specs {
+		spec, ok := s.(*ast.ValueSpec)
+		if !ok {
+			continue
+		}
+		if len(spec.Values) > 0 {
+			typ, values = spec.Type, spec.Values
+		}
+
+		iota := constant.MakeInt64(int64(i))
+		for j, name := range spec.Names {
+			if name.Name == "_" || j >= len(values) {
+				continue
+			}
+			value, ok := w.constantValue(values[j], iota)
+			if ok && typ != nil {
+				value, ok = convertConstant(value, typ)
+			}
+			if !ok {
+				continue
+			}
+			if w.constants == nil {
+				w.constants = map[string]constant.Value{}
+			}
+			w.constants[name.Name] = value
+
+			w.Constants = append(w.Constants, ConstantDef{
+				Name: name.Name, Value: constantString(values[j], value), Type: constantType(typ), File: w.file,
+			})
+		}
+	}
+}
+
+// visitVars records variables initialized with literals like `var V = 3`
+// as untyped constants, so they are never enum values.
+func (w *Walker) visitVars(decl *ast.GenDecl) {
+	for _, s := range decl.Specs {
+		spec, ok := s.(*ast.ValueSpec)
+		if !ok || len(spec.Names) < 1 || len(spec.Values) < 1 {
+			continue
+		}
+		lit, ok := spec.Values[0].(*ast.BasicLit)
+		if !ok {
+			continue
+		}
+		w.Constants = append(w.Constants, ConstantDef{
+			Name: spec.Names[0].Name, Value: removeQuotes(lit.Value), File: w.file,
+		})
 	}
-	name := astValueSpec.Names[0].Name
-	var value string
-	switch v := astValueSpec.Values[0].(type) {
+}
+
+// constantValue evaluates the constant expression made of literals, iota
+// and the file constants declared before.
+func (w *Walker) constantValue(expr ast.Expr, iota constant.Value) (constant.Value, bool) {
+	switch v := expr.(type) {
 	case *ast.BasicLit:
-		value = removeQuotes(v.Value)
+		value := constant.MakeFromLiteral(v.Value, v.Kind, 0)
+		return value, value.Kind() != constant.Unknown
+	case *ast.Ident:
+		switch v.Name {
+		case "iota":
+			return iota, true
+		case "true", "false":
+			return constant.MakeBool(v.Name == "true"), true
+		}
+		value, ok := w.constants[v.Name]
+		return value, ok
+	case *ast.ParenExpr:
+		return w.constantValue(v.X, iota)
+	case *ast.UnaryExpr:
+		x, ok := w.constantValue(v.X, iota)
+		if !ok {
+			return nil, false
+		}
+		value := constant.UnaryOp(v.Op, x, 0)
+		return value, value.Kind() != constant.Unknown
+	case *ast.BinaryExpr:
+		x, ok := w.constantValue(v.X, iota)
+		if !ok {
+			return nil, false
+		}
+		y, ok := w.constantValue(v.Y, iota)
+		if !ok {
+			return nil, false
+		}
+		return binaryOp(x, v.Op, y)
+	case *ast.CallExpr:
+		// conversions like `Status(1)` keep the value, builtin calls like `len("a")`
+		// and `string(rune)` conversions change it.
+		if fn, ok := v.Fun.(*ast.Ident); ok && constantBuiltins[fn.Name] || len(v.Args) != 1 {
+			return nil, false
+		}
+		value, ok := w.constantValue(v.Args[0], iota)
+		if !ok {
+			return nil, false
+		}
+		return convertConstant(value, v.Fun)
+	}
+	return nil, false
+}
+
+// convertConstant converts the value to the basic type like `float64`, so `float64(1) / 2`
+// is a float division. Values of other types are kept.
+func convertConstant(value constant.Value, typ ast.Expr) (constant.Value, bool) {
+	ident, ok := typ.(*ast.Ident)
+	if !ok {
+		return value, true
+	}
+	switch ident.Name {
+	case "float32", "float64":
+		value = constant.ToFloat(value)
+	case "complex64", "complex128":
+		value = constant.ToComplex(value)
+	case "int", "int8", "int16", "int32", "int64", "rune",
+		"uint", "uint8", "uint16", "uint32", "uint64", "uintptr", "byte":
+		value = constant.ToInt(value)
 	default:
-		return
+		return value, true
 	}
+	return value, value.Kind() != constant.Unknown
+}
+
+// constantBuiltins are builtin functions and conversions changing constant values.
+var constantBuiltins = map[string]bool{
+	"len": true, "cap": true, "real": true, "imag": true, "complex": true,
+	"min": true, "max": true, "string": true,
+}
+
+// binaryOp is constant.BinaryOp, constant.Shift and constant.Compare
+// not panicking on invalid operands.
+func binaryOp(x constant.Value, op token.Token, y constant.Value) (value constant.Value, ok bool) {
+	defer func() {
+		if recover() != nil {
+			value, ok = nil, false
+		}
+	}()
+
+	switch op {
+	case token.SHL, token.SHR:
+		s, exact := constant.Uint64Val(y)
+		if !exact {
+			return nil, false
+		}
+		value = constant.Shift(x, op, uint(s))
+	case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+		value = constant.MakeBool(constant.Compare(x, op, y))
+	case token.QUO:
+		if x.Kind() == constant.Int && y.Kind() == constant.Int {
+			// QUO_ASSIGN forces integer division, float conversions like `float64(1)` are Float values.
+			op = token.QUO_ASSIGN
+		}
+		value = constant.BinaryOp(x, op, y)
+	default:
+		value = constant.BinaryOp(x, op, y)
+	}
+	return value, value.Kind() != constant.Unknown
+}
 
-	w.Constants = append(w.Constants, ConstantDef{
-		Name: name, Value: value,
-	})
+// constantString renders the constant value, literals are kept as written
+// without quotes like `0x10` or `enum-1`.
+func constantString(expr ast.Expr, value constant.Value) string {
+	if lit, ok := expr.(*ast.BasicLit); ok {
+		return removeQuotes(lit.Value)
+	}
+	switch value.Kind() {
+	case constant.String:
+		return constant.StringVal(value)
+	case constant.Float:
+		f, _ := constant.Float64Val(value)
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	default:
+		return value.ExactString()
+	}
 }
 
-func (w *Walker) visitTypeSpec(astTypeSpec *ast.TypeSpec) {
+// constantType renders the declared constant type like `MyEnum` or `time.Duration`,
+// empty for untyped constants.
+func constantType(t ast.Expr) string {
+	switch v := t.(type) {
+	case *ast.Ident:
+		return v.Name
+	case *ast.SelectorExpr:
+		if x, ok := v.X.(*ast.Ident); ok {
+			return x.Name + "." + v.Sel.Name
+		}
+	}
+	return ""
+}
+
+func (w *Walker) visitImport(astImportSpec *ast.ImportSpec) {
+	i := ImportDef{
+		Path:     removeQuotes(astImportSpec.Path.Value),
+		Comments: append(parseComments(astImportSpec.Doc), parseComments(astImportSpec.Comment)...),
+	}
+	if astImportSpec.Name != nil {
+		i.Name = astImportSpec.Name.Name
+	}
+	if w.fset != nil {
+		i.Pos = w.fset.Position(astImportSpec.Pos())
+	}
+
+	w.Imports = append(w.Imports, i)
+}
+
+func (w *Walker) visitFunc(astFuncDecl *ast.FuncDecl) {
+	f := FuncDef{
+		Name:      astFuncDecl.Name.Name,
+		Signature: funcSignature(astFuncDecl.Type),
+		Comments:  parseComments(astFuncDecl.Doc),
+		File:      w.file,
+	}
+	if astFuncDecl.Recv != nil && len(astFuncDecl.Recv.List) > 0 {
+		f.Receiver = receiverName(astFuncDecl.Recv.List[0].Type)
+	}
+
+	w.Funcs = append(w.Funcs, f)
+}
+
+// visitTypeSpec records the struct or the type declaration with the doc comment.
+func (w *Walker) visitTypeSpec(astTypeSpec *ast.TypeSpec, doc *ast.CommentGroup) {
 	structName := astTypeSpec.Name.Name
 
 	switch v := astTypeSpec.Type.(type) {
@@ -62,12 +325,16 @@ func (w *Walker) visitTypeSpec(astTypeSpec *ast.TypeSpec) {
 
 		s := StructDef{
 			Name:     structName,
-			Comments: parseComments(astTypeSpec.Doc)}
+			Comments: parseComments(doc),
+			File:     w.file}
 
 		for _, astField := range astFields {
 			field, err := parseField(astField)
 			if err != nil {
-				log.Fatalf("failed to parse struct %s: %v", structName, err)
+				if w.Err == nil {
+					w.Err = errors.Wrapf(err, "failed to parse struct %s", structName)
+				}
+				continue
 			}
 			if field != nil {
 				s.Fields = append(s.Fields, *field)
@@ -76,19 +343,99 @@ func (w *Walker) visitTypeSpec(astTypeSpec *ast.TypeSpec) {
 
 		w.Structs = append(w.Structs, s)
 
-	case *ast.ArrayType:
-		// skip array aliases for now
-		return
+	default:
+		// the declared type is being resolved, so references to itself are not expanded.
+		t, err := parseTypeExpr(v, map[*ast.TypeSpec]bool{astTypeSpec: true})
+		if err != nil {
+			// skip types we can't represent, like func or chan types.
+			return
+		}
+
+		typeDef := TypeDef{
+			Name:     structName,
+			Type:     t,
+			Alias:    astTypeSpec.Assign.IsValid(),
+			Comments: parseComments(doc),
+			File:     w.file,
+		}
+		if iface, ok := v.(*ast.InterfaceType); ok {
+			typeDef.Methods, typeDef.Embedded = w.interfaceMethods(iface)
+		}
+		w.Types = append(w.Types, typeDef)
+	}
+
+}
+
+// interfaceMethods returns interface methods and embedded interfaces names.
+func (w *Walker) interfaceMethods(iface *ast.InterfaceType) ([]FuncDef, []string) {
+	var methods []FuncDef
+	var embedded []string
+	for _, field := range iface.Methods.List {
+		ft, ok := field.Type.(*ast.FuncType)
+		if !ok {
+			embedded = append(embedded, types.ExprString(field.Type))
+			continue
+		}
+		for _, name := range field.Names {
+			methods = append(methods, FuncDef{
+				Name:      name.Name,
+				Signature: funcSignature(ft),
+				Comments:  parseComments(field.Doc),
+				File:      w.file,
+			})
+		}
+	}
+	return methods, embedded
+}
+
+// funcSignature renders function type without parameter names, like `func(string, ...int) (int, error)`.
+func funcSignature(ft *ast.FuncType) string {
+	params := fieldTypes(ft.Params)
+	results := fieldTypes(ft.Results)
+
+	signature := "func(" + strings.Join(params, ", ") + ")"
+	switch {
+	case len(results) == 1:
+		signature += " " + results[0]
+	case len(results) > 1:
+		signature += " (" + strings.Join(results, ", ") + ")"
+	}
+	return signature
+}
 
+// fieldTypes renders types of the params list, a type is repeated for each name.
+func fieldTypes(fields *ast.FieldList) []string {
+	if fields == nil {
+		return nil
+	}
+	var list []string
+	for _, f := range fields.List {
+		t := types.ExprString(f.Type)
+		n := len(f.Names)
+		if n == 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			list = append(list, t)
+		}
+	}
+	return list
+}
+
+// receiverName renders method receiver type like `Struct` or `*Struct`.
+func receiverName(t ast.Expr) string {
+	switch v := t.(type) {
 	case *ast.Ident:
-		// *ast.TypeSpec can also be a type alias
-		return
-	case *ast.InterfaceType:
-		return
+		return v.Name
+	case *ast.StarExpr:
+		return "*" + receiverName(v.X)
+	case *ast.ParenExpr:
+		return receiverName(v.X)
+	case *ast.IndexExpr:
+		return receiverName(v.X)
 	default:
-		log.Fatalf("unexpected type for typeSpec: %s, %+v: %T", structName, astTypeSpec, astTypeSpec.Type)
+		return ""
 	}
-
 }
 
 func parseField(astField *ast.Field) (*FieldDef, error) {
@@ -105,6 +452,11 @@ func parseField(astField *ast.Field) (*FieldDef, error) {
 	}
 
 	fieldType, err := parseFieldType(astField.Type)
+	var unsupported *unsupportedTypeError
+	if errors.As(err, &unsupported) {
+		// skip fields we can't represent, like func or chan fields.
+		return nil, nil
+	}
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to parse field %s type", fieldName)
 	}
@@ -140,6 +492,56 @@ func parseComments(group *ast.CommentGroup) []string {
 	return comments
 }
 
+// parseBuildConstraint finds `//go:build` or legacy `// +build` lines
+// before the package clause and returns them as a single expression.
+func parseBuildConstraint(file *ast.File) string {
+	var plusBuild []constraint.Expr
+	for _, group := range file.Comments {
+		if group.Pos() >= file.Package {
+			break
+		}
+		for _, c := range group.List {
+			switch {
+			case constraint.IsGoBuild(c.Text):
+				if expr, err := constraint.Parse(c.Text); err == nil {
+					return expr.String()
+				}
+			case constraint.IsPlusBuild(c.Text):
+				if expr, err := constraint.Parse(c.Text); err == nil {
+					plusBuild = append(plusBuild, expr)
+				}
+			}
+		}
+	}
+
+	if len(plusBuild) == 0 {
+		return ""
+	}
+	expr := plusBuild[0]
+	for _, e := range plusBuild[1:] {
+		expr = &constraint.AndExpr{X: expr, Y: e}
+	}
+	return expr.String()
+}
+
+var generatedRegexp = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// isGenerated reports whether the file has the generated code header
+// before the package clause, see https://golang.org/s/generatedcode.
+func isGenerated(file *ast.File) bool {
+	for _, group := range file.Comments {
+		if group.Pos() >= file.Package {
+			break
+		}
+		for _, c := range group.List {
+			if generatedRegexp.MatchString(c.Text) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func removeQuotes(s string) string {
 	if len(s) < 2 {
 		//panic(fmt.Sprintf("bad input for removing quotes: %s", s))
@@ -196,6 +598,13 @@ func parseTags(astTag *ast.BasicLit) (Tag, error) {
 }
 
 func parseFieldType(t ast.Expr) (Type, error) {
+	return parseTypeExpr(t, map[*ast.TypeSpec]bool{})
+}
+
+// parseTypeExpr parses the type expression resolving named types declared in the file.
+// Declarations in resolving are being resolved already, AliasType of the types
+// referencing them is not set, so self-referencing types like `type List []List` terminate.
+func parseTypeExpr(t ast.Expr, resolving map[*ast.TypeSpec]bool) (Type, error) {
 	switch v := t.(type) {
 	case *ast.InterfaceType:
 		return TypeInterfaceValue{}, nil
@@ -215,7 +624,17 @@ func parseFieldType(t ast.Expr) (Type, error) {
 
 		switch decl := v.Obj.Decl.(type) {
 		case *ast.TypeSpec:
-			aliasType, err := parseFieldType(decl.Type)
+			if resolving[decl] {
+				return typeCustom, nil
+			}
+			resolving[decl] = true
+			aliasType, err := parseTypeExpr(decl.Type, resolving)
+			delete(resolving, decl)
+			var unsupported *unsupportedTypeError
+			if errors.As(err, &unsupported) {
+				// the type can't be represented, like `type Handler func()`.
+				return typeCustom, nil
+			}
 			if err != nil {
 				return nil, fmt.Errorf("parse alias type: %w", err)
 			}
@@ -227,25 +646,29 @@ func parseFieldType(t ast.Expr) (Type, error) {
 
 		return typeCustom, nil
 	case *ast.SelectorExpr:
-		return TypeCustom{Name: v.Sel.Name, Expr: t}, nil
+		typeCustom := TypeCustom{Name: v.Sel.Name, Expr: t}
+		if x, ok := v.X.(*ast.Ident); ok {
+			typeCustom.Qualifier = x.Name
+		}
+		return typeCustom, nil
 	case *ast.ArrayType:
-		t, err := parseFieldType(v.Elt)
+		t, err := parseTypeExpr(v.Elt, resolving)
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to parse array nested type %+v", t)
 		}
 		return TypeArray{InnerType: t}, nil
 	case *ast.StarExpr:
-		t, err := parseFieldType(v.X)
+		t, err := parseTypeExpr(v.X, resolving)
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to parse star expr type %+v", t)
 		}
 		return TypePointer{InnerType: t}, nil
 	case *ast.MapType:
-		kt, err := parseFieldType(v.Key)
+		kt, err := parseTypeExpr(v.Key, resolving)
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to parse map key type %+v", v.Key)
 		}
-		vt, er := parseFieldType(v.Value)
+		vt, er := parseTypeExpr(v.Value, resolving)
 		if er != nil {
 			return nil, errors.Wrapf(er, "failed to parse map value type %+v", v.Value)
 		}
@@ -253,10 +676,19 @@ func parseFieldType(t ast.Expr) (Type, error) {
 	case *ast.StructType:
 		return nil, nil
 	default:
-		return nil, fmt.Errorf("unexpected %+[1]v with type %[1]T", t)
+		return nil, &unsupportedTypeError{expr: t}
 	}
 }
 
+// unsupportedTypeError is returned for types the model can't represent, like func or chan types.
+type unsupportedTypeError struct {
+	expr ast.Expr
+}
+
+func (e *unsupportedTypeError) Error() string {
+	return fmt.Sprintf("unsupported type %s", types.ExprString(e.expr))
+}
+
 func parseFieldName(fieldNames []*ast.Ident) string {
 	if len(fieldNames) == 0 {
 		return ""
@@ -264,6 +696,34 @@ func parseFieldName(fieldNames []*ast.Ident) string {
 	return fieldNames[0].Name
 }
 
+// importPathToName guesses package name from its import path.
+func importPathToName(importPath string) string {
+	name := path.Base(importPath)
+	// skip major version suffix like github.com/go-chi/chi/v5
+	if len(name) > 1 && name[0] == 'v' && isDigits(name[1:]) {
+		if dir := path.Dir(importPath); dir != "." {
+			name = path.Base(dir)
+		}
+	}
+	name = strings.TrimPrefix(name, "go-")
+	// cut at the first char that can't be a part of identifier, e.g. gopkg.in/yaml.v3
+	if i := strings.IndexFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
 func simpleType(fieldType string) Type {
 	switch fieldType {
 	case "string", "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "float32", "float64", "bool", "byte":
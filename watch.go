@@ -0,0 +1,199 @@
+package astparser
+
+import (
+	"context"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WatchOptions configures Watch polling.
+type WatchOptions struct {
+	// Interval is the polling interval, defaults to 300ms.
+	Interval time.Duration
+	// Debounce is a quiet period after the last change before an event
+	// is emitted, so a burst of saves results in a single event. Defaults to 200ms.
+	Debounce time.Duration
+}
+
+// WatchEvent describes changes of the watched files.
+type WatchEvent struct {
+	// Files are the changed files parsed again.
+	Files []ParsedFile
+	// Deleted contains paths of the removed files.
+	Deleted []string
+	// Packages are all the packages after the change.
+	Packages []*Package
+	// Changes are the changed declarations.
+	Changes ChangeSet
+	// Err is set if the changed files failed to load. Other fields are empty then.
+	Err error
+}
+
+// Watch loads the input dirs files and watches them for changes.
+// See Loader.Watch.
+func Watch(ctx context.Context, cfg Config, opts WatchOptions) (<-chan WatchEvent, error) {
+	l, err := NewLoader(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return l.Watch(ctx, opts)
+}
+
+// Watch polls the input dirs recursively for changed go files and emits an event
+// once changes settle down. The first load is made before Watch returns if the
+// loader hasn't loaded files yet. Changes of files the loader doesn't load produce
+// no events. The returned channel is closed when ctx is done.
+func (l *Loader) Watch(ctx context.Context, opts WatchOptions) (<-chan WatchEvent, error) {
+	if opts.Interval <= 0 {
+		opts.Interval = 300 * time.Millisecond
+	}
+	if opts.Debounce <= 0 {
+		opts.Debounce = 200 * time.Millisecond
+	}
+
+	// take the snapshot first so changes made during the load are not missed.
+	snapshot, err := l.snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	if l.parsed == nil {
+		err = l.load(ctx)
+	}
+	l.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan WatchEvent)
+	go l.watch(ctx, opts, snapshot, events)
+	return events, nil
+}
+
+func (l *Loader) watch(ctx context.Context, opts WatchOptions, snapshot map[string]fileStamp, events chan<- WatchEvent) {
+	defer close(events)
+
+	ticks := l.ticks
+	if ticks == nil {
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	changed, deleted := map[string]bool{}, map[string]bool{}
+	var lastChange time.Time
+	for {
+		// the debounce is measured by tick times.
+		var now time.Time
+		select {
+		case <-ctx.Done():
+			return
+		case now = <-ticks:
+		}
+
+		current, err := l.snapshot()
+		if err != nil {
+			// input dirs could be temporary unavailable, e.g. during checkout.
+			continue
+		}
+
+		if diffSnapshots(snapshot, current, changed, deleted) {
+			snapshot = current
+			lastChange = now
+			continue
+		}
+		if len(changed) == 0 && len(deleted) == 0 || now.Sub(lastChange) < opts.Debounce {
+			continue
+		}
+
+		event, ok := l.flush(ctx, changed, deleted)
+		changed, deleted = map[string]bool{}, map[string]bool{}
+		if !ok {
+			continue
+		}
+
+		select {
+		case events <- event:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// flush updates the loader with collected changes, returns false if no loaded files were affected.
+func (l *Loader) flush(ctx context.Context, changed, deleted map[string]bool) (WatchEvent, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	changes, files, removed, err := l.update(ctx, sortedKeys(changed), sortedKeys(deleted))
+	if err != nil {
+		return WatchEvent{Err: err}, ctx.Err() == nil
+	}
+	if len(files) == 0 && len(removed) == 0 {
+		return WatchEvent{}, false
+	}
+
+	return WatchEvent{Files: files, Deleted: removed, Packages: l.packages, Changes: changes}, true
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// snapshot returns stamps of all go files in the input dir trees by path in ParsedFile.Path form.
+func (l *Loader) snapshot() (map[string]fileStamp, error) {
+	stamps := map[string]fileStamp{}
+	for _, src := range l.sources {
+		// watch subdirectories even if they are not loaded, the loader filters them out.
+		src.recursive = true
+		err := src.walk(func(name string, d fs.DirEntry) error {
+			if !strings.HasSuffix(name, ".go") {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				// removed after listing.
+				return nil
+			}
+			stamps[src.displayPath(name)] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return stamps, nil
+}
+
+// diffSnapshots collects changed and deleted files and reports whether there were any.
+func diffSnapshots(old, current map[string]fileStamp, changed, deleted map[string]bool) bool {
+	var found bool
+	for p, stamp := range current {
+		if prev, ok := old[p]; !ok || !prev.modTime.Equal(stamp.modTime) || prev.size != stamp.size {
+			changed[p] = true
+			delete(deleted, p)
+			found = true
+		}
+	}
+	for p := range old {
+		if _, ok := current[p]; !ok {
+			deleted[p] = true
+			delete(changed, p)
+			found = true
+		}
+	}
+	return found
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,108 @@
+package astparser
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "astparser-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	event := filepath.Join(dir, "event.go")
+	if err := ioutil.WriteFile(event, []byte("package models\n\ntype Event struct{}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "nested"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := NewLoader(Config{InputDir: dir})
+	if err != nil {
+		t.Fatalf("NewLoader() error = %v", err)
+	}
+	// polls are driven by the test, tick times measure the debounce.
+	ticks := make(chan time.Time)
+	l.ticks = ticks
+	debounce := time.Second
+	now := time.Now()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := l.Watch(ctx, WatchOptions{Debounce: debounce})
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+
+	tick := func(d time.Duration) {
+		t.Helper()
+		select {
+		case ticks <- now:
+			now = now.Add(d)
+		case <-time.After(5 * time.Second):
+			t.Fatal("watch doesn't poll")
+		}
+	}
+	// the first poll finds the changes, the last one after the debounce emits the event.
+	poll := func() WatchEvent {
+		t.Helper()
+		tick(debounce / 2)
+		tick(debounce)
+		tick(0)
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(5 * time.Second):
+			t.Fatal("no watch event")
+		}
+		return WatchEvent{}
+	}
+
+	// nested package files are not loaded, so they produce no events.
+	if err := ioutil.WriteFile(filepath.Join(dir, "nested", "other.go"), []byte("package nested\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(event, []byte("package models\n\ntype Event struct {\n\tID int\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	e := poll()
+	if e.Err != nil {
+		t.Fatalf("unexpected error %v", e.Err)
+	}
+	if len(e.Files) != 1 || e.Files[0].Path != event {
+		t.Errorf("unexpected files %+v", e.Files)
+	}
+	want := []Change{{Kind: ChangeAdded, Package: "models", Struct: "Event", Name: "ID", File: event}}
+	if !reflect.DeepEqual(e.Changes.Fields, want) {
+		t.Errorf("\nhave %+v, \nwant %+v", e.Changes.Fields, want)
+	}
+
+	if err := os.Remove(event); err != nil {
+		t.Fatal(err)
+	}
+	e = poll()
+	if !reflect.DeepEqual(e.Deleted, []string{event}) || len(e.Changes.Structs) != 1 || e.Changes.Structs[0].Kind != ChangeRemoved {
+		t.Errorf("unexpected event %+v", e)
+	}
+
+	// invalid files are reported instead of stopping the watch.
+	if err := ioutil.WriteFile(event, []byte("package models\n\ntype Event struct {\n\tID int `json`\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if e = poll(); e.Err == nil {
+		t.Errorf("expected error, got event %+v", e)
+	}
+
+	cancel()
+	for range events {
+	}
+}